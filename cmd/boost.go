@@ -0,0 +1,445 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/sys/unix"
+)
+
+// boostCmd represents the boost command
+var boostCmd = &cobra.Command{
+	Use:   "boost [-n] -p PROFILE [--for DURATION] (PID|NAME|-- COMMAND [ARGUMENT]...)",
+	Short: "Boost processes for a while",
+	Long: `Apply a profile to some running processes for a while, then restore them
+
+The process group of PID, the process groups of the processes named NAME, or
+the COMMAND started with its ARGUMENT(S), are boosted with PROFILE until the
+--for duration expires, the processes exit or the boost command is
+interrupted. Their previous attributes and cgroup are then restored.
+The running control command, if any, does not apply any rule to the boosted
+process groups, until restored.
+Only superuser can boost the processes of other users.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if cmd.ArgsLenAtDash() == 0 { // COMMAND [ARGUMENT]...
+			return cobra.MinimumNArgs(1)(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args) // PID or NAME
+	},
+	DisableFlagsInUseLine: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		// Bind shared flags
+		if err := viper.BindPFlags(cmd.LocalNonPersistentFlags()); err != nil {
+			return err
+		}
+		if err := checkLiveProcFS(); err != nil {
+			return err
+		}
+		if viper.GetString("preset") == "" {
+			return fmt.Errorf("%w: profile required", ErrInvalid)
+		}
+		if viper.GetDuration("for") < 0 {
+			return fmt.Errorf("%w: duration: %v", ErrInvalid, viper.GetDuration("for"))
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		viper.Set("tag", "boost")
+		// Debug output
+		debugOutput(cmd)
+		// Real job goes here
+		presetCache = GetPresetCache() // get cache content, once for all goroutines
+		if err := setCapabilities(true); err != nil {
+			cmd.PrintErrln(err)
+		}
+		std := &Streams{Stdin: nil, Stdout: cmd.OutOrStdout(), Stderr: cmd.ErrOrStderr()}
+		var err error
+		if cmd.ArgsLenAtDash() == 0 {
+			err = doBoostCommand("", args, std)
+		} else {
+			err = doBoostCmd("", args[0], std)
+		}
+		if err := setCapabilities(false); err != nil {
+			cmd.PrintErrln(err)
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) { // exit as the command did
+			os.Exit(exitErr.ExitCode())
+		}
+		fatal(wrap(err))
+	},
+}
+
+func init() {
+	// Persistent flags
+	// Local flags
+	fs := boostCmd.Flags()
+	fs.SortFlags = false
+	fs.SetInterspersed(false)
+	addDryRunFlag(boostCmd)
+	fs.StringP("preset", "p", "", "apply this `PROFILE`")
+	fs.Duration("for", 0, "restore after `DURATION` (0 for never)")
+	boostCmd.InheritedFlags().SortFlags = false
+}
+
+// boostedProc is some boosted process, with its attributes and unit before
+// boost.
+type boostedProc struct {
+	Pid       int
+	Comm      string
+	StartTime uint64
+	Cgroup    string
+	Unit      string
+	Runtime   BaseProfile
+}
+
+// Match returns whether p is the same process.
+func (b *boostedProc) Match(p *Proc) bool {
+	return b.StartTime == p.StartTime && b.Comm == p.Comm
+}
+
+// BoostedGroup is some boosted process group.
+type BoostedGroup struct {
+	Pgrp   int
+	Pinned int // pid pinned in the running control command
+	Procs  []boostedProc
+}
+
+// pgrpFilter selects the processes of the process group.
+func pgrpFilter(pgrp int) ProcFilter {
+	return ProcFilter{
+		FilterProc: FilterProc{
+			Filter: func(p *Proc, err error) bool {
+				return err == nil && p.Pgrp == pgrp
+			},
+			Message: fmt.Sprintf("processes in group %d", pgrp),
+		},
+		Stat: func(stat *ProcStat) bool {
+			return stat.Pgrp == pgrp
+		},
+	}
+}
+
+// Boost records the process group of pid, then applies the profile.
+func Boost(pc *PresetCache, pid int, profile string, tag string, std *Streams) (*BoostedGroup, error) {
+	if !(pc.HasPreset("profile", profile)) {
+		return nil, fmt.Errorf("%w: profile %s", ErrNotFound, profile)
+	}
+	found := SelectedProcs([]int{pid}, GetFilterer("all"))
+	if len(found) == 0 {
+		return nil, fmt.Errorf("%w: process %d", ErrNotFound, pid)
+	}
+	g := &BoostedGroup{Pgrp: found[0].Pgrp, Pinned: pid}
+	for _, p := range FilteredProcs(pgrpFilter(g.Pgrp)) {
+		g.Procs = append(g.Procs, boostedProc{
+			Pid:       p.Pid,
+			Comm:      p.Comm,
+			StartTime: p.StartTime,
+			Cgroup:    p.Cgroup,
+			Unit:      p.Unit,
+			Runtime:   p.Runtime(),
+		})
+	}
+	// do not let the running control command undo the boost
+	sendBoostRequest("pin", pid)
+	if _, err := pc.ApplyPreset(pid, profile, tag, std); err != nil {
+		return g, err
+	}
+	return g, nil
+}
+
+// records returns the boosted processes, by pid.
+func (g *BoostedGroup) records() map[int]boostedProc {
+	result := make(map[int]boostedProc)
+	for _, b := range g.Procs {
+		result[b.Pid] = b
+	}
+	return result
+}
+
+// alive returns the boosted processes still running.
+func (g *BoostedGroup) alive() (result []*Proc) {
+	records := g.records()
+	for _, p := range FilteredProcs(pgrpFilter(g.Pgrp)) {
+		if b, found := records[p.Pid]; found && b.Match(p) {
+			result = append(result, p)
+		}
+	}
+	return
+}
+
+// Alive returns whether some boosted process still runs.
+func (g *BoostedGroup) Alive() bool {
+	return len(g.alive()) > 0
+}
+
+// attachCommand returns the command that moves back the process into the
+// unit of b.
+func (g *BoostedGroup) attachCommand(job *ProcJob, b boostedProc) Command {
+	var tokens []string
+	manager := "--system"
+	if regexes["managed"].MatchString(b.Cgroup) {
+		manager = "--user"
+		tokens = job.prefix()
+	} else if os.Getuid() != 0 {
+		tokens = append(tokens, "$SUDO")
+	}
+	tokens = append(tokens,
+		"busctl", "call", "--quiet", manager,
+		"org.freedesktop.systemd1", "/org/freedesktop/systemd1",
+		"org.freedesktop.systemd1.Manager", "AttachProcessesToUnit",
+		"ssau", b.Unit, "/", "1", strconv.Itoa(b.Pid),
+	)
+	return NewCommand(tokens...)
+}
+
+// restoreJob returns the job restoring the attributes and the unit of the
+// boosted processes still running, if any.
+func (g *BoostedGroup) restoreJob() *ProcGroupJob {
+	groupjob := NewProcGroupJob(g.alive())
+	if groupjob == nil {
+		return nil
+	}
+	records := g.records()
+	for _, job := range groupjob.Jobs {
+		b, pid := records[job.Proc.Pid], job.Proc.Pid
+		runtime := job.Proc.Runtime()
+		job.Request.Proc = job.Proc
+		job.Rule = Rule{BaseProfile: b.Runtime}
+		job.Rule.SetCredentials()
+		if b.Unit != "" && b.Unit != job.Proc.Unit {
+			job.AddCommand(g.attachCommand(job, b))
+		}
+		if runtime.Nice != b.Runtime.Nice {
+			job.AdjustNice(pid)
+		}
+		if runtime.IOClass != b.Runtime.IOClass || runtime.IONice != b.Runtime.IONice {
+			job.AdjustIOClassIONice(pid)
+		}
+		if runtime.Sched != b.Runtime.Sched || runtime.RTPrio != b.Runtime.RTPrio {
+			job.AdjustSchedRTPrio(pid)
+		}
+		if runtime.OomScoreAdj != b.Runtime.OomScoreAdj {
+			job.AddProfileCommand("oom_score_adj", choom(b.Runtime.OomScoreAdj, pid))
+		}
+		groupjob.Commands = append(groupjob.Commands, job.Commands...)
+	}
+	return groupjob
+}
+
+// Restore restores the attributes and the unit of the boosted processes
+// still running. The running control command, if any, applies again its
+// rules to the process group.
+func (g *BoostedGroup) Restore(tag string, std *Streams) error {
+	groupjob := g.restoreJob()
+	if groupjob == nil {
+		return nil
+	}
+	err := groupjob.Run(tag, std)
+	if Contains(groupjob.Pids, g.Pinned) { // otherwise forgotten on exit
+		sendBoostRequest("unpin", g.Pinned)
+	}
+	return err
+}
+
+// sendBoostRequest pins or unpins the process group of pid, in the running
+// control commands, if any.
+func sendBoostRequest(command string, pid int) {
+	if viper.GetBool("dry-run") {
+		return
+	}
+	var sent []string
+	for _, scope := range controlScopes {
+		path := controlSocket(scope)
+		if Contains(sent, path) {
+			continue
+		}
+		sent = append(sent, path)
+		resp, err := SendCtlRequest(path, CtlRequest{Command: command, Pid: pid})
+		switch {
+		case err != nil:
+			debug(err) // control command not running
+		case !(resp.Ok):
+			warn(fmt.Errorf("%w: %s: %s", ErrFailure, command, resp.Error))
+		}
+	}
+}
+
+// boostTargets returns a process per process group to boost, from the pid or
+// the name of the processes. Unless superuser, only the processes of the
+// calling user are found by name.
+func boostTargets(arg string) (result []int, err error) {
+	if pid, err := strconv.Atoi(arg); err == nil {
+		if pid <= 0 {
+			return nil, fmt.Errorf("%w: bad pid: %s", ErrInvalid, arg)
+		}
+		return []int{pid}, nil
+	}
+	uid := os.Getuid()
+	groups := make(map[int]bool)
+	for _, p := range FilteredProcs(ProcFilter{
+		FilterProc: FilterProc{
+			Filter: func(p *Proc, err error) bool {
+				return err == nil && (uid == 0 || p.Uid == uid) &&
+					(p.Comm == arg || p.RuleName() == arg)
+			},
+			Message: fmt.Sprintf("processes named %s", arg),
+		},
+		Stat: func(stat *ProcStat) bool {
+			return stat.Comm == arg || stat.RuleName() == arg
+		},
+	}) {
+		if !(groups[p.Pgrp]) {
+			groups[p.Pgrp] = true
+			result = append(result, p.Pid)
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("%w: process %s", ErrNotFound, arg)
+	}
+	return
+}
+
+// boostSignals returns the channel receiving the signals that end the boost.
+func boostSignals() chan os.Signal {
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, os.Interrupt, unix.SIGTERM, unix.SIGHUP)
+	return signalChan
+}
+
+// holdBoost waits until the duration expires, all the boosted processes
+// exit, done is closed or some signal is received, then restores the boosted
+// process groups.
+func holdBoost(groups []*BoostedGroup, done <-chan struct{}, signalChan <-chan os.Signal, tag string, std *Streams) (err error) {
+	var expired <-chan time.Time
+	if duration := viper.GetDuration("for"); duration > 0 {
+		timer := time.NewTimer(duration)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+wait:
+	for {
+		select {
+		case <-expired:
+			break wait
+		case <-done:
+			break wait
+		case sig := <-signalChan:
+			if viper.GetBool("dry-run") || viper.GetBool("verbose") {
+				inform(tag, fmt.Sprintf("%v received", sig))
+			}
+			break wait
+		case <-ticker.C:
+			if len(Filter(groups, (*BoostedGroup).Alive)) == 0 {
+				return nil // nothing to restore
+			}
+		}
+	}
+	if viper.GetBool("dry-run") || viper.GetBool("verbose") {
+		inform(tag, "Restoring...")
+	}
+	for _, g := range groups {
+		if failure := g.Restore(tag, std); failure != nil && err == nil {
+			err = failure
+		}
+	}
+	return
+}
+
+func doBoostCmd(tag string, arg string, std *Streams) error {
+	pids, err := boostTargets(arg)
+	if err != nil {
+		return err
+	}
+	signalChan := boostSignals() // restore when interrupted while boosting
+	defer signal.Stop(signalChan)
+	var groups []*BoostedGroup
+	for _, pid := range pids {
+		g, err := Boost(&presetCache, pid, viper.GetString("preset"), tag, std)
+		if g != nil {
+			groups = append(groups, g)
+		}
+		if err != nil {
+			warn(err)
+		}
+	}
+	if len(groups) == 0 {
+		return fmt.Errorf("%w: process %s", ErrNotFound, arg)
+	}
+	return holdBoost(groups, nil, signalChan, tag, std)
+}
+
+// doBoostCommand starts the command inside its own process group, in the
+// foreground of the terminal, if any, and boosts it. The exit error of the
+// command, if any, is returned.
+func doBoostCommand(tag string, args []string, std *Streams) error {
+	if viper.GetBool("dry-run") {
+		inform(tag, fmt.Sprintf("boosting %v with %s", args, viper.GetString("preset")))
+		return nil
+	}
+	c := exec.Command(args[0], args[1:]...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	c.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	_, err := unix.IoctlGetTermios(int(os.Stdin.Fd()), unix.TCGETS)
+	terminal := err == nil
+	if terminal {
+		c.SysProcAttr.Foreground, c.SysProcAttr.Ctty = true, 0
+	}
+	signalChan := boostSignals()
+	defer signal.Stop(signalChan)
+	if err := c.Start(); err != nil {
+		return fmt.Errorf("%w: %v", ErrFailure, err)
+	}
+	done := make(chan struct{})
+	var status error
+	go func() {
+		status = c.Wait()
+		close(done)
+	}()
+	g, err := Boost(&presetCache, c.Process.Pid, viper.GetString("preset"), tag, std)
+	if err != nil {
+		warn(err)
+	}
+	if g != nil {
+		nonfatal(holdBoost([]*BoostedGroup{g}, done, signalChan, tag, std))
+	}
+	<-done
+	if terminal { // take the terminal back
+		signal.Ignore(unix.SIGTTOU)
+		nonfatal(wrap(unix.IoctlSetPointerInt(int(os.Stdin.Fd()), unix.TIOCSPGRP, unix.Getpgrp())))
+		signal.Reset(unix.SIGTTOU)
+	}
+	var exitErr *exec.ExitError
+	if errors.As(status, &exitErr) {
+		return exitErr
+	}
+	return nil
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
@@ -0,0 +1,211 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestBoostTargets(t *testing.T) {
+	self := os.Getpid()
+	comm, err := os.ReadFile("/proc/self/comm")
+	if err != nil {
+		t.Skip(err)
+	}
+	tests := []struct {
+		name    string
+		arg     string
+		want    []int // nil when any
+		wantErr error
+	}{
+		{"pid", strconv.Itoa(self), []int{self}, nil},
+		{"zero pid", "0", nil, ErrInvalid},
+		{"negative pid", "-1", nil, ErrInvalid},
+		{"name", strings.TrimSpace(string(comm)), nil, nil},
+		{"unknown name", "nicy-missing-process", nil, ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := boostTargets(tt.arg)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(got) == 0 {
+				t.Fatal("got no process")
+			}
+			if tt.want != nil && !(len(got) == len(tt.want) && got[0] == tt.want[0]) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// boostTestdata boosts the process group of firefox found in testdata, with
+// dry-run.
+func boostTestdata(t *testing.T) *BoostedGroup {
+	t.Helper()
+	useTestdata(t)
+	defer viper.Set("dry-run", viper.GetBool("dry-run"))
+	viper.Set("dry-run", true)
+	g, err := Boost(testPresetCache(t), 4244, "Web-Browser", "boost", &Streams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestBoost(t *testing.T) {
+	defer func(s *ProcState) { procState = s }(procState)
+	procState = nil
+	g := boostTestdata(t)
+	if g.Pgrp != 4243 || g.Pinned != 4244 {
+		t.Errorf("got group %d pinning %d, want 4243 pinning 4244", g.Pgrp, g.Pinned)
+	}
+	// runtime attributes and unit recorded before boost
+	var want []boostedProc
+	for _, p := range FilteredProcs(pgrpFilter(4243)) {
+		want = append(want, boostedProc{
+			Pid: p.Pid, Comm: p.Comm, StartTime: p.StartTime,
+			Cgroup: p.Cgroup, Unit: p.Unit, Runtime: p.Runtime(),
+		})
+	}
+	if !(reflect.DeepEqual(g.Procs, want)) {
+		t.Errorf("got %+v, want %+v", g.Procs, want)
+	}
+	if len(g.Procs) != 2 || g.Procs[1].Runtime.Nice != 5 {
+		t.Errorf("got %+v, want 4243 and 4244 with nice 5", g.Procs)
+	}
+	pc := testPresetCache(t)
+	if _, err := Boost(pc, 4244, "Unknown", "boost", &Streams{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v with unknown profile, want %v", err, ErrNotFound)
+	}
+	if _, err := Boost(pc, 4245, "Web-Browser", "boost", &Streams{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v with unknown pid, want %v", err, ErrNotFound)
+	}
+}
+
+func TestBoostedGroupRestore(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(b *boostedProc)
+		want   []string // utilities, nil when nothing to restore
+	}{
+		{"unchanged", func(b *boostedProc) {}, []string{}},
+		{"nice", func(b *boostedProc) { b.Runtime.Nice = 7 }, []string{"renice"}},
+		{"oom score", func(b *boostedProc) { b.Runtime.OomScoreAdj = 0 }, []string{"choom"}},
+		{"unit", func(b *boostedProc) { b.Unit = "app-other.scope" }, []string{"busctl"}},
+		{"exited", func(b *boostedProc) { b.StartTime++ }, nil},
+		{"executed", func(b *boostedProc) { b.Comm = "sh" }, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := boostTestdata(t)
+			for i := range g.Procs {
+				if g.Procs[i].Pid == 4243 {
+					tt.change(&g.Procs[i])
+				} else {
+					g.Procs[i].StartTime++ // exited
+				}
+			}
+			job := g.restoreJob()
+			if tt.want == nil {
+				if job != nil || g.Alive() {
+					t.Errorf("got job %v, want nothing to restore", job)
+				}
+				return
+			}
+			if job == nil {
+				t.Fatal("got nothing to restore")
+			}
+			if !(reflect.DeepEqual(job.Pids, []int{4243})) {
+				t.Errorf("got pids %v, want [4243]", job.Pids)
+			}
+			got := []string{}
+			for _, c := range job.Commands {
+				if !(c.skipRuntime || c.IsEmpty()) { // as run
+					got = append(got, c.Utility())
+				}
+			}
+			if !(reflect.DeepEqual(got, tt.want)) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendBoostRequest(t *testing.T) {
+	useTestdata(t)
+	defer func(s *ProcState) { procState = s }(procState)
+	procState = NewProcState(0)
+	defer viper.Set("socket", viper.GetString("socket"))
+	viper.Set("socket", filepath.Join(t.TempDir(), "control.sock"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// running control command
+	c := &controller{
+		filter: GetFilterer("all"),
+		ctx:    ctx,
+		cancel: cancel,
+		procs:  make(chan []*Proc, 8),
+	}
+	server, err := NewCtlServer(controlSocket("user"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	c.handle(server)
+	go server.Serve(ctx)
+	selected := func() []int {
+		ready, _ := procState.Select(FilteredProcs(pgrpFilter(4243)))
+		return pids(ready)
+	}
+	if got, want := selected(), []int{4243, 4244}; !(reflect.DeepEqual(got, want)) {
+		t.Fatalf("got %v before boost, want %v", got, want)
+	}
+	// no rule applied while boosted
+	sendBoostRequest("pin", 4244)
+	if got := selected(); len(got) != 0 {
+		t.Errorf("got %v while boosted, want none", got)
+	}
+	// rules applied again once restored
+	sendBoostRequest("unpin", 4244)
+	if got, want := selected(), []int{4243, 4244}; !(reflect.DeepEqual(got, want)) {
+		t.Errorf("got %v once restored, want %v", got, want)
+	}
+	select {
+	case batch := <-c.procs:
+		if !(Contains(pids(batch), 4243)) {
+			t.Errorf("got %v scanned once restored, want 4243", pids(batch))
+		}
+	default:
+		t.Error("got no scan once restored")
+	}
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
@@ -0,0 +1,348 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+)
+
+// defaultBudgetWindow is the time after which the budget of a cgroup resets,
+// unless set.
+const defaultBudgetWindow = 24 * time.Hour
+
+// Budget is the CPU time the processes inside the nicy slice of some cgroup
+// can use during each window. Once used up, the properties of the Fallback
+// cgroup apply to the slice, or the slice is frozen, until the window resets.
+// The nicy slices inside the service manager of the other users are tracked
+// too, when root controls their processes.
+type Budget struct {
+	CPU      time.Duration `yaml:"cpu" json:"cpu"`
+	Window   time.Duration `yaml:"window,omitempty" json:"window,omitempty"`
+	Fallback string        `yaml:"fallback,omitempty" json:"fallback,omitempty"`
+	Freeze   bool          `yaml:"freeze,omitempty" json:"freeze,omitempty"`
+}
+
+// Validate checks the budget and sets default values.
+func (b *Budget) Validate(pc *PresetCache) error {
+	if b.CPU <= 0 {
+		return fmt.Errorf("%w: budget: cpu: %v", ErrInvalid, b.CPU)
+	}
+	switch {
+	case b.Window == 0:
+		b.Window = defaultBudgetWindow
+	case b.Window < time.Minute:
+		return fmt.Errorf("%w: budget: window: %v", ErrInvalid, b.Window)
+	}
+	if b.Fallback != "" && !(pc.HasPreset("cgroup", b.Fallback)) {
+		return fmt.Errorf("%w: budget: cgroup %s", ErrNotFound, b.Fallback)
+	}
+	if b.Fallback == "" && !(b.Freeze) {
+		return fmt.Errorf("%w: budget: nothing to do", ErrInvalid)
+	}
+	return nil
+}
+
+// WindowStart returns when the window including t started. Windows of whole
+// days start at local midnight.
+func (b *Budget) WindowStart(t time.Time) time.Time {
+	window := b.Window
+	if window <= 0 {
+		window = defaultBudgetWindow
+	}
+	if window%(24*time.Hour) == 0 {
+		days := int64(window / (24 * time.Hour))
+		y, m, d := t.Date()
+		// days since epoch, whatever the daylight saving time
+		elapsed := time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / 86400
+		return time.Date(y, m, d-int(elapsed%days), 0, 0, 0, 0, t.Location())
+	}
+	return t.Truncate(window)
+}
+
+// budgetState is what some budget used during its current window.
+type budgetState struct {
+	Start     time.Time      `yaml:"start" json:"start"`
+	Used      uint64         `yaml:"used" json:"used"`                       // in microseconds
+	Last      uint64         `yaml:"last" json:"last"`                       // usage_usec last read
+	Users     map[int]uint64 `yaml:"users,omitempty" json:"users,omitempty"` // inside the slices of other users
+	Exhausted bool           `yaml:"exhausted,omitempty" json:"exhausted,omitempty"`
+	Clamped   []string       `yaml:"clamped,omitempty" json:"clamped,omitempty"` // fallback properties set
+	Frozen    bool           `yaml:"frozen,omitempty" json:"frozen,omitempty"`
+}
+
+// Budgets tracks the CPU time used by the nicy slices of the cgroups with
+// some budget, saving it into a state file.
+type Budgets struct {
+	mu     sync.Mutex
+	path   string
+	states map[string]*budgetState
+}
+
+// budgetFile returns the path of the state file.
+func budgetFile() string {
+	return filepath.Join(viper.GetString("runtimedir"), "budgets.yaml")
+}
+
+// NewBudgets returns the budgets, loaded from the state file at path, if
+// any.
+func NewBudgets(path string) (*Budgets, error) {
+	b := &Budgets{path: path, states: make(map[string]*budgetState)}
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return b, nil
+	case err != nil:
+		return b, fmt.Errorf("%w: %v", ErrFailure, err)
+	}
+	if err := yaml.Unmarshal(data, &b.states); err != nil {
+		return b, fmt.Errorf("%w: %s: %v", ErrInvalid, path, err)
+	}
+	return b, nil
+}
+
+// save writes the state file, unless dry-run.
+func (b *Budgets) save() error {
+	if viper.GetBool("dry-run") {
+		return nil
+	}
+	data, err := yaml.Marshal(b.states)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrFailure, err)
+	}
+	if err := os.WriteFile(b.path, data, 0644); err != nil {
+		return fmt.Errorf("%w: %v", ErrFailure, err)
+	}
+	return nil
+}
+
+// Update reads the CPU time used inside each nicy slice with budget, then
+// clamps the slices whose budget is used up, and restores those whose window
+// reset.
+func (b *Budgets) Update(pc *PresetCache, now time.Time) (restored []string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	seen := make(map[string]bool)
+	for key := range pc.Cgroups {
+		cgroup, _ := pc.Cgroup(key)
+		budget := cgroup.Budget
+		if budget == nil {
+			continue
+		}
+		seen[key] = true
+		state, found := b.states[key]
+		if !(found) {
+			state = &budgetState{Start: budget.WindowStart(now)}
+			b.states[key] = state
+		}
+		if start := budget.WindowStart(now); !(start.Equal(state.Start)) {
+			state.Start, state.Used = start, 0
+			if state.Exhausted {
+				state.Exhausted = false
+				inform(key, fmt.Sprintf("budget window reset, restoring cgroup %s", key))
+				nonfatal(b.restore(pc, key, state))
+				restored = append(restored, key)
+			}
+		}
+		used, running := state.read(key, found)
+		if !(running) {
+			continue
+		}
+		state.Used += used
+		if !(state.Exhausted) && state.Used >= uint64(budget.CPU.Microseconds()) {
+			state.Exhausted = true
+			inform(key, fmt.Sprintf(
+				"%v of CPU time used, clamping cgroup %s", budget.CPU, key,
+			))
+			nonfatal(b.clamp(pc, key, budget, state))
+		}
+	}
+	for key, state := range b.states {
+		if seen[key] {
+			continue
+		}
+		if state.Exhausted { // budget removed
+			nonfatal(b.restore(pc, key, state))
+			restored = append(restored, key)
+		}
+		delete(b.states, key)
+	}
+	if len(seen) > 0 || len(restored) > 0 {
+		nonfatal(b.save())
+	}
+	return
+}
+
+// read returns the CPU time used inside the nicy slices for cgroup key since
+// last read, when counted, and whether some slice is running.
+func (s *budgetState) read(key string, counted bool) (used uint64, running bool) {
+	calling := os.Getuid()
+	lasts := map[int]uint64{calling: s.Last}
+	for uid, usage := range s.Users {
+		lasts[uid] = usage
+	}
+	s.Last, s.Users = 0, nil
+	for _, uid := range sliceUids() {
+		usage, err := CgroupCPUUsage(userSliceCgroup(uid, key))
+		if err != nil { // slice not running
+			debug(err)
+			continue
+		}
+		running = true
+		switch last := lasts[uid]; {
+		case !(counted):
+		case usage >= last:
+			used += usage - last
+		default: // slice started again
+			used += usage
+		}
+		if uid == calling {
+			s.Last = usage
+			continue
+		}
+		if s.Users == nil {
+			s.Users = make(map[int]uint64)
+		}
+		s.Users[uid] = usage
+	}
+	return
+}
+
+// clamp applies the properties of the fallback cgroup to the nicy slice for
+// cgroup key, or freezes it, remembering what was applied into state.
+func (b *Budgets) clamp(pc *PresetCache, key string, budget *Budget, state *budgetState) (err error) {
+	if budget.Fallback != "" {
+		fallback, _ := pc.Cgroup(budget.Fallback)
+		properties := fallback.ScopeProperties()
+		for _, property := range properties {
+			name, _, _ := strings.Cut(property, "=")
+			if !(Contains(state.Clamped, name)) {
+				state.Clamped = append(state.Clamped, name)
+			}
+		}
+		err = SetSliceProperties(key, key, properties)
+	}
+	if budget.Freeze {
+		state.Frozen = true
+		if failure := freezeSlice(key, true); failure != nil && err == nil {
+			err = failure
+		}
+	}
+	return
+}
+
+// restore applies again the properties of cgroup key to its nicy slice,
+// resetting the other properties applied when clamped, and thaws it when
+// frozen.
+func (b *Budgets) restore(pc *PresetCache, key string, state *budgetState) (err error) {
+	cgroup, _ := pc.Cgroup(key)
+	properties := cgroup.ScopeProperties()
+	set := make(map[string]bool)
+	for _, property := range properties {
+		name, _, _ := strings.Cut(property, "=")
+		set[name] = true
+	}
+	for _, name := range state.Clamped {
+		if !(set[name]) {
+			properties = append(properties, name+"=") // reset
+		}
+	}
+	err = SetSliceProperties(key, key, properties)
+	if state.Frozen {
+		if failure := freezeSlice(key, false); failure != nil && err == nil {
+			err = failure
+		}
+	}
+	state.Clamped, state.Frozen = nil, false
+	return
+}
+
+// freezeSlice freezes or thaws the nicy slice for cgroup key, unless dry-run.
+func freezeSlice(key string, frozen bool) error {
+	if viper.GetBool("dry-run") || viper.GetBool("verbose") {
+		inform(key, fmt.Sprintf("nicy-%s.slice: frozen: %v", key, frozen))
+	}
+	if viper.GetBool("dry-run") {
+		return nil
+	}
+	return freezeNicySlices(key, frozen)
+}
+
+// Reapply clamps again the nicy slice for cgroup key, when its budget is used
+// up, once its properties changed.
+func (b *Budgets) Reapply(pc *PresetCache, key string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if state, found := b.states[key]; found && state.Exhausted {
+		if cgroup, _ := pc.Cgroup(key); cgroup.Budget != nil {
+			nonfatal(b.clamp(pc, key, cgroup.Budget, state))
+		}
+	}
+}
+
+// Revert restores all the clamped nicy slices. The CPU time used is kept, and
+// the slices are clamped again on next update, when the budget is still used
+// up.
+func (b *Budgets) Revert(pc *PresetCache) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for key, state := range b.states {
+		if !(state.Exhausted) {
+			continue
+		}
+		nonfatal(b.restore(pc, key, state))
+		state.Exhausted = false
+	}
+	nonfatal(b.save())
+}
+
+// Exhausted returns the cgroup keys whose budget is used up.
+func (b *Budgets) Exhausted() (result []string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for key, state := range b.states {
+		if state.Exhausted {
+			result = append(result, key)
+		}
+	}
+	sort.Strings(result)
+	return
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
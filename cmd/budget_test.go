@@ -0,0 +1,109 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestBudgetWindowStart(t *testing.T) {
+	cest := time.FixedZone("CEST", 2*60*60)
+	date := func(day, hh, mm int, loc *time.Location) time.Time {
+		return time.Date(2026, time.October, day, hh, mm, 0, 0, loc)
+	}
+	tests := []struct {
+		name   string
+		window time.Duration
+		t      time.Time
+		want   time.Time
+	}{
+		{"default", 0, date(16, 15, 30, time.UTC), date(16, 0, 0, time.UTC)},
+		{"day", 24 * time.Hour, date(16, 0, 0, time.UTC), date(16, 0, 0, time.UTC)},
+		{"day before midnight", 24 * time.Hour, date(16, 23, 59, time.UTC), date(16, 0, 0, time.UTC)},
+		{"day local midnight", 24 * time.Hour, date(16, 1, 30, cest), date(16, 0, 0, cest)},
+		{"two days first", 48 * time.Hour, date(16, 12, 0, time.UTC), date(16, 0, 0, time.UTC)},
+		{"two days second", 48 * time.Hour, date(17, 12, 0, time.UTC), date(16, 0, 0, time.UTC)},
+		{"week", 7 * 24 * time.Hour, date(16, 12, 0, time.UTC), date(15, 0, 0, time.UTC)},
+		{"week last day", 7 * 24 * time.Hour, date(21, 23, 0, time.UTC), date(15, 0, 0, time.UTC)},
+		{"week next", 7 * 24 * time.Hour, date(22, 0, 0, time.UTC), date(22, 0, 0, time.UTC)},
+		{"hour", time.Hour, date(16, 15, 30, time.UTC), date(16, 15, 0, time.UTC)},
+		{"ten minutes", 10 * time.Minute, date(16, 15, 39, time.UTC), date(16, 15, 30, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := Budget{Window: tt.window}
+			if got := b.WindowStart(tt.t); !(got.Equal(tt.want)) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBudgetStateRead(t *testing.T) {
+	root := t.TempDir()
+	defer viper.Set("cgroupfs.root", viper.GetString("cgroupfs.root"))
+	viper.Set("cgroupfs.root", root)
+	defer func(managed bool) { manageUserSlices = managed }(manageUserSlices)
+	manageUserSlices = true
+	// usages inside the slice of the calling user, then of some other user,
+	// only seen by root
+	others := os.Getuid() == 0
+	write := func(uid int, usage uint64) {
+		dir := cgroupFile(userSliceCgroup(uid, "cpu66"), "")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		data := fmt.Sprintf("usage_usec %d\nuser_usec 0\n", usage)
+		if err := os.WriteFile(filepath.Join(dir, "cpu.stat"), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var state budgetState
+	if _, running := state.read("cpu66", true); running {
+		t.Errorf("got running without any slice")
+	}
+	write(os.Getuid(), 100)
+	write(1000, 50)
+	if used, running := state.read("cpu66", false); used != 0 || !(running) {
+		t.Errorf("got %d used, running %v on first read, want 0, true", used, running)
+	}
+	write(os.Getuid(), 150)
+	write(1000, 80)
+	want := uint64(50)
+	if others {
+		want += 30
+	}
+	if used, _ := state.read("cpu66", true); used != want {
+		t.Errorf("got %d used, want %d", used, want)
+	}
+	// slice of other user stopped
+	if err := os.RemoveAll(cgroupFile(userSliceCgroup(1000, "cpu66"), "")); err != nil {
+		t.Fatal(err)
+	}
+	write(os.Getuid(), 200)
+	if used, _ := state.read("cpu66", true); used != 50 {
+		t.Errorf("got %d used once slice of other user stopped, want 50", used)
+	}
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
@@ -17,13 +17,13 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package cmd
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
-	"sort"
-	"strings"
 	"sync"
+	"time"
 
 	"github.com/spf13/viper"
 	"gopkg.in/yaml.v2"
@@ -36,6 +36,7 @@ type PresetCache struct {
 	Rules      map[string][]Rule    `yaml:"rules,flow" json:"rules"`
 	Origin     string               `yaml:"-" json:"-"`
 	RuleFilter FilterProc           `yaml:"-" json:"-"`
+	restrict   *restriction         // for the presets of some user
 }
 
 func NewPresetCache() PresetCache {
@@ -47,7 +48,7 @@ func NewPresetCache() PresetCache {
 	pc.RuleFilter = FilterProc{
 		Filter: func(p *Proc, err error) bool {
 			if err == nil {
-				if _, err = pc.Rule(strings.Split(p.Comm, `:`)[0]); err == nil {
+				if _, err = pc.Rule(p.RuleName()); err == nil {
 					return true
 				}
 			}
@@ -81,15 +82,21 @@ func (pc *PresetCache) WriteCache(cacheFile string) error {
 	return err
 }
 
-func (pc *PresetCache) LoadFromConfig() (err error) {
-	var cfg Config
-	for _, root := range Reverse(viper.GetStringSlice("confdirs")) {
+func (pc *PresetCache) LoadFromConfig() error {
+	return pc.LoadFromDirs(viper.GetStringSlice("confdirs"))
+}
+
+// LoadFromDirs reads the configuration files inside dirs, given in order of
+// precedence.
+func (pc *PresetCache) LoadFromDirs(dirs []string) (err error) {
+	for _, root := range Reverse(dirs) {
 		path := filepath.Join(root, confName+"."+confType)
 		if exists(path) {
 			if viper.GetBool("verbose") {
 				inform("cache", "reading configuration file", path+"...")
 			}
-			if cfg, err = NewConfig(path); err == nil {
+			cfg, cfgErr := NewConfig(path)
+			if cfgErr == nil {
 				wg := getWaitGroup()
 				wg.Add(3)
 				go func() {
@@ -107,7 +114,9 @@ func (pc *PresetCache) LoadFromConfig() (err error) {
 				wg.Wait()
 				continue
 			}
-			nonfatal(err)
+			if err == nil {
+				err = cfgErr
+			}
 		}
 	}
 	pc.Origin = "config"
@@ -118,6 +127,103 @@ func (pc *PresetCache) LoadFromConfig() (err error) {
 	return
 }
 
+// Merge returns a new cache with the presets of other added to those of pc.
+// Unless override, the presets already in pc are kept, ignoring the same
+// presets in other.
+func (pc *PresetCache) Merge(other *PresetCache, override bool) *PresetCache {
+	merged := NewPresetCache()
+	MergePresets(merged.Cgroups, pc.Cgroups, other.Cgroups, override)
+	MergePresets(merged.Profiles, pc.Profiles, other.Profiles, override)
+	MergePresets(merged.Rules, pc.Rules, other.Rules, override)
+	merged.Origin = "config"
+	merged.Date = timestamp()
+	return &merged
+}
+
+// Validate checks that every rule expands, with existing profile and cgroup,
+// and that the variants of the presets are valid.
+func (pc *PresetCache) Validate() error {
+	for key, rules := range pc.Rules {
+		rule := ActivePreset(rules)
+		if err := pc.validateVariants(&rule.Variants); err != nil {
+			return fmt.Errorf("%w: rule %s: %v", ErrInvalid, key, err)
+		}
+		if err := pc.Expand(&rule); err != nil {
+			return fmt.Errorf("%w: rule %s: %v", ErrInvalid, key, err)
+		}
+		if err := rule.Threads.Validate(rule.BaseProfile); err != nil {
+			return fmt.Errorf("%w: rule %s: %v", ErrInvalid, key, err)
+		}
+	}
+	for key, profiles := range pc.Profiles {
+		profile := ActivePreset(profiles)
+		if err := pc.validateVariants(&profile.Variants); err != nil {
+			return fmt.Errorf("%w: profile %s: %v", ErrInvalid, key, err)
+		}
+	}
+	for key, cgroups := range pc.Cgroups {
+		cgroup := ActivePreset(cgroups)
+		if err := pc.validateVariants(&cgroup.Variants); err != nil {
+			return fmt.Errorf("%w: cgroup %s: %v", ErrInvalid, key, err)
+		}
+		if cgroup.Budget != nil {
+			if err := cgroup.Budget.Validate(pc); err != nil {
+				return fmt.Errorf("%w: cgroup %s: %v", ErrInvalid, key, err)
+			}
+		}
+		if cgroup.Reclaim != nil {
+			if err := cgroup.Reclaim.Validate(); err != nil {
+				return fmt.Errorf("%w: cgroup %s: %v", ErrInvalid, key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ReloadPresetCache builds a new cache from configuration files. The cache is
+// returned only when the files are readable and the presets valid.
+func ReloadPresetCache() (*PresetCache, error) {
+	pc := NewPresetCache()
+	if err := pc.LoadFromConfig(); err != nil {
+		return nil, err
+	}
+	if err := pc.Validate(); err != nil {
+		return nil, err
+	}
+	return &pc, nil
+}
+
+// reloadConfig reads again the configuration files, for the settings outside
+// the presets.
+func reloadConfig() (*viper.Viper, error) {
+	v := viper.New()
+	v.SetConfigType(confType)
+	dirs := viper.GetStringSlice("confdirs")
+	var paths []string
+	for i := len(dirs) - 1; i >= 0; i-- {
+		paths = append(paths, filepath.Join(dirs[i], confName+"."+confType))
+	}
+	if cfgFile != "" {
+		paths = append(paths, cfgFile)
+	}
+	for _, path := range paths {
+		if !(exists(path)) {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err == nil {
+			err = v.MergeConfig(bytes.NewReader(data))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalid, path, err)
+		}
+	}
+	return v, nil
+}
+
+// GetPresetCache returns the presets, read from the cache file unless forced
+// or missing, then from the configuration files. It exits when the presets
+// are not valid.
 func GetPresetCache() PresetCache {
 	if !viper.GetBool("force") && len(presetCache.Date) > 0 {
 		return presetCache
@@ -127,6 +233,7 @@ func GetPresetCache() PresetCache {
 	if !viper.GetBool("force") {
 		if err = pc.LoadFromCache(viper.GetString("cache")); err == nil {
 			pc.Origin = "file"
+			fatal(pc.Validate())
 			return pc
 		}
 	}
@@ -138,7 +245,8 @@ func GetPresetCache() PresetCache {
 			nonfatal(failed(err))
 		}
 	}
-	pc.LoadFromConfig()
+	nonfatal(pc.LoadFromConfig())
+	fatal(pc.Validate())
 	return pc
 }
 
@@ -190,16 +298,25 @@ func notFound(category string, key string) error {
 	return err
 }
 
+// Rule returns the rule for key, with its active variant applied.
 func (pc *PresetCache) Rule(key string) (Rule, error) {
-	return GetPreset(pc.Rules, key, "rule")
+	rule, err := GetPreset(pc.Rules, key, "rule")
+	applyRule(&rule, time.Now())
+	return rule, err
 }
 
+// Profile returns the profile for key, with its active variant applied.
 func (pc *PresetCache) Profile(key string) (Profile, error) {
-	return GetPreset(pc.Profiles, key, "profile")
+	profile, err := GetPreset(pc.Profiles, key, "profile")
+	applyProfile(&profile, time.Now())
+	return profile, err
 }
 
+// Cgroup returns the cgroup for key, with its active variant applied.
 func (pc *PresetCache) Cgroup(key string) (Cgroup, error) {
-	return GetPreset(pc.Cgroups, key, "cgroup")
+	cgroup, err := GetPreset(pc.Cgroups, key, "cgroup")
+	applyCgroup(&cgroup, time.Now())
+	return cgroup, err
 }
 
 func (pc *PresetCache) RawRule(input *Request) (Rule, error) {
@@ -318,6 +435,8 @@ func (pc *PresetCache) SliceProperties(rule Rule) (result []string) {
 	return
 }
 
+// RequestRule returns the rule to apply for the request, resolved with the
+// variants active at the current time.
 func (pc *PresetCache) RequestRule(input *Request) Rule {
 	rule, err := pc.RawRule(input)
 	if err != nil {
@@ -328,6 +447,7 @@ func (pc *PresetCache) RequestRule(input *Request) Rule {
 	trace("RawRule", input.Name, rule)
 	fatal(pc.Expand(&rule))
 	trace("Expand", input.Name, rule)
+	pc.restrict.apply(input, &rule)
 	if input.Preset == "cgroup-only" {
 		rule.CgroupOnly()
 	}
@@ -407,14 +527,136 @@ func (pc *PresetCache) GetFilterer(scope string) ProcFilterer {
 			Message: filter.String(),
 		},
 		Scope: scope,
+		Stat: func(stat *ProcStat) bool {
+			_, err := pc.Rule(stat.RuleName())
+			return err == nil
+		},
 	})
 }
 
+// maxAncestors limits the walk up the process tree, whatever the depth.
+const maxAncestors = 64
+
+// Inheritable returns whether some rule may apply to descendant processes.
+func (pc *PresetCache) Inheritable() bool {
+	if viper.GetBool("inherit") {
+		return true
+	}
+	for _, rules := range pc.Rules {
+		for _, rule := range rules {
+			if rule.Inherit {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Resolve sets the rule for the processes without a rule of their own, when
+// inherited from the closest ancestor with a rule, or, with group option,
+// when shared by some other member of the process group. Processes missing
+// from procs, like ancestors outside of scope, are read from /proc.
+func (pc *PresetCache) Resolve(procs []*Proc) {
+	var (
+		inherit = pc.Inheritable()
+		group   = viper.GetBool("group")
+		stats   = make(map[int]*ProcStat, len(procs))
+	)
+	if !(inherit || group) {
+		return
+	}
+	for _, p := range procs {
+		stats[p.Pid] = &p.ProcStat
+	}
+	read := func(pid int) *ProcStat {
+		if stat, ok := stats[pid]; ok {
+			return stat
+		}
+		stat := &ProcStat{}
+		if pid <= 0 || stat.Read(pid) != nil {
+			stat = nil
+		}
+		stats[pid] = stat
+		return stat
+	}
+	own := func(stat *ProcStat) (Rule, bool) {
+		rule, err := pc.Rule(stat.RuleName())
+		return rule, err == nil
+	}
+	if inherit {
+		for _, p := range procs {
+			if _, ok := own(&p.ProcStat); ok {
+				continue
+			}
+			stat := read(p.Ppid)
+			for gen := 1; stat != nil && gen <= maxAncestors; gen++ {
+				if rule, ok := own(stat); ok {
+					if rule.Inherit || viper.GetBool("inherit") {
+						depth := rule.Depth
+						if depth == 0 {
+							depth = viper.GetInt("depth")
+						}
+						if depth == 0 || gen <= depth {
+							p.rule = rule.RuleKey
+						}
+					}
+					break // closest ancestor with a rule only
+				}
+				stat = read(stat.Ppid)
+			}
+		}
+	}
+	if group {
+		shared := make(map[int]string) // rule of lowest matching pid
+		for _, p := range procs {
+			if _, ok := shared[p.Pgrp]; ok {
+				continue
+			}
+			if _, err := pc.Rule(p.RuleName()); err == nil {
+				shared[p.Pgrp] = p.RuleName()
+			}
+		}
+		for _, p := range procs {
+			if _, err := pc.Rule(p.RuleName()); err == nil {
+				continue
+			}
+			if name, ok := shared[p.Pgrp]; ok {
+				p.rule = name
+			} else if leader := read(p.Pgrp); leader != nil {
+				if _, ok := own(leader); ok { // leader missing from procs
+					p.rule = leader.RuleName()
+				}
+			}
+		}
+	}
+}
+
 func (pc *PresetCache) FilteredProcs(inputs <-chan []*Proc, outputs chan<- []*Proc, wg *sync.WaitGroup) {
 	defer wg.Done()
 	for procs := range inputs {
+		pc.Resolve(procs)
+		var ignored []*Proc
 		filtered := Filter(procs, func(p *Proc) bool {
-			return pc.RuleFilter.Filter(p, nil)
+			if pc.RuleFilter.Filter(p, nil) {
+				if procState.Match(p) { // count once per process
+					controlMetrics.Matched(p.RuleName())
+				}
+				return true
+			}
+			ignored = append(ignored, p)
+			return false
+		})
+		// boost foreground process groups without rule too
+		boosted := foregroundProcs(ignored, filtered)
+		filtered = append(filtered, boosted...)
+		for _, p := range ignored {
+			if !(Contains(boosted, p)) {
+				procState.Ignore(p)
+			}
+		}
+		// read the other attributes of the processes getting some job only
+		filtered = Filter(filtered, func(p *Proc) bool {
+			return p.Load() == nil
 		})
 		if len(filtered) > 0 {
 			outputs <- filtered
@@ -429,10 +671,26 @@ func (pc *PresetCache) DiffReview(job *ProcGroupJob) (count int, err error) {
 	}
 	leader := job.leader // process group leader only
 	// get rule and set leader rule for reference
-	leader.Rule = pc.RequestRule(leader.Request)
+	if pc.foregroundOnly(leader) {
+		leader.Rule = Rule{}
+	} else {
+		leader.Rule = pc.RequestRule(leader.Request)
+	}
 	running := Rule{BaseProfile: leader.Runtime()}
+	// boost or restore foreground process group
+	restoring := pc.Boost(leader, running.BaseProfile)
+	// do not enforce attributes changed by hand
+	pinned := procState.Pinned(leader.Proc, running.BaseProfile)
+	ResetMatching(&pinned.BaseProfile, &leader.Rule)
+	// do not promote again processes demoted by the realtime watchdog
+	if procState.Demoted(leader.Proc) {
+		leader.Rule.Sched, leader.Rule.RTPrio = "", 0
+	}
 	// review diff
 	job.Diff, count = leader.Rule.GetDiff(running)
+	count += restoring
+	// review the threads with some profile of their own
+	count += job.reviewThreads(leader.Rule.Threads, procState.Demoted(leader.Proc))
 	// check cgroup: processes are easily movable when running inside
 	// session scope and some managed nicy slice
 	if job.Diff.HasCgroupKey() {
@@ -464,26 +722,12 @@ func (pc *PresetCache) RawGroupJobs(inputs <-chan []*Proc, output chan<- *ProcGr
 			child.Add(1)
 			go func() {
 				defer child.Done()
-				jobs := ProcToProcJob(s)
-				if len(jobs) > 0 {
-					// sort content
-					sort.SliceStable(jobs, func(i, j int) bool {
-						return jobs[i].Proc.Pid < jobs[j].Proc.Pid
-					})
-					leader := jobs[0]
-					groupjob := &ProcGroupJob{ // new group job
-						Pgrp:   leader.Proc.Pgrp,
-						Pids:   []int{leader.Proc.Pid},
-						Diff:   Rule{},
-						Jobs:   []*ProcJob{leader},
-						leader: leader,
-					}
-					for _, job := range jobs[1:] { // add content
-						groupjob.Pids = append(groupjob.Pids, job.Proc.Pid)
-						groupjob.Jobs = append(groupjob.Jobs, job)
-					}
+				if groupjob := NewProcGroupJob(s); groupjob != nil {
 					if count, _ := pc.DiffReview(groupjob); count > 0 {
+						procState.Unsettle(groupjob)
 						output <- groupjob
+					} else {
+						procState.Settle(groupjob)
 					}
 				}
 			}()
@@ -517,4 +761,54 @@ func (pc *PresetCache) GenerateGroupJobs(inputs <-chan []*Proc, output chan<- *P
 	return
 }
 
+// ApplyPreset applies at once the preset to the process group of pid. The
+// job is returned, even when there is nothing to do.
+func (pc *PresetCache) ApplyPreset(pid int, preset string, tag string, std *Streams) (*ProcGroupJob, error) {
+	switch {
+	case preset == "":
+		preset = "auto"
+	case preset == "auto", preset == "cgroup-only":
+	case !(pc.HasPreset("profile", preset)):
+		return nil, fmt.Errorf("%w: profile %s", ErrNotFound, preset)
+	}
+	found := SelectedProcs([]int{pid}, GetFilterer("all"))
+	if len(found) == 0 {
+		return nil, fmt.Errorf("%w: process %d", ErrNotFound, pid)
+	}
+	pgrp := found[0].Pgrp
+	groupjob := NewProcGroupJob(FilteredProcs(pgrpFilter(pgrp)))
+	if groupjob == nil {
+		return nil, fmt.Errorf("%w: process group %d", ErrNotFound, pgrp)
+	}
+	for _, job := range groupjob.Jobs {
+		job.Request.Preset = preset
+	}
+	if count, err := pc.DiffReview(groupjob); err != nil || count == 0 {
+		return groupjob, err
+	}
+	if err := groupjob.PrepareAdjust(); err != nil {
+		return groupjob, err
+	}
+	if err := groupjob.Run(tag, std); err != nil {
+		return groupjob, err
+	}
+	procState.Settle(groupjob)
+	return groupjob, nil
+}
+
+// SendGroupJobs sends the group jobs for procs, without closing output.
+func (pc *PresetCache) SendGroupJobs(procs []*Proc, output chan<- *ProcGroupJob) {
+	inputs := make(chan []*Proc, 1)
+	inputs <- procs
+	close(inputs)
+	groupjobs := make(chan *ProcGroupJob, 8)
+	wg := getWaitGroup()
+	wg.Add(1)
+	go pc.GenerateGroupJobs(inputs, groupjobs, &wg)
+	for groupjob := range groupjobs {
+		output <- groupjob
+	}
+	wg.Wait()
+}
+
 // vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
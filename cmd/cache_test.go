@@ -0,0 +1,182 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// writeConfig writes content as the configuration file of some directory
+// and returns the directory.
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, confName+"."+confType)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestReloadPresetCache(t *testing.T) {
+	const valid = `presets:
+  cgroups:
+    cpu33:
+      CPUQuota: 33%
+  appgroups:
+    none: {}
+    Web-Browser:
+      assignments:
+        - firefox
+      profile:
+        nice: -3
+        cgroup: cpu33
+  rules:
+    firefox:
+      profile: Web-Browser
+`
+	tests := []struct {
+		name    string
+		configs []string // from system to user
+		want    []string // rules
+		wantErr error
+	}{
+		{"valid", []string{valid}, []string{"firefox"}, nil},
+		{"merged", []string{valid, "presets:\n  rules:\n    mpv:\n      nice: 5\n"}, []string{"firefox", "mpv"}, nil},
+		{"no file", nil, nil, nil},
+		{"unreadable", []string{"presets: [\n"}, nil, ErrInvalid},
+		{"invalid in user file", []string{valid, "presets:\n  rules: 3\n"}, nil, ErrInvalid},
+		{"unknown profile", []string{valid, "presets:\n  rules:\n    mpv:\n      profile: Video\n"}, nil, ErrInvalid},
+		{"unknown cgroup", []string{valid, "presets:\n  rules:\n    mpv:\n      cgroup: cpu50\n"}, nil, ErrInvalid},
+	}
+	defer viper.Set("confdirs", viper.GetStringSlice("confdirs"))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var dirs []string
+			for _, content := range tt.configs {
+				dirs = append([]string{writeConfig(t, content)}, dirs...)
+			}
+			viper.Set("confdirs", dirs)
+			pc, err := ReloadPresetCache()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if pc != nil {
+					t.Error("got cache with error")
+				}
+				return
+			}
+			if got := len(pc.Rules); got != len(tt.want) {
+				t.Errorf("got %d rules, want %v", got, tt.want)
+			}
+			for _, key := range tt.want {
+				if _, found := pc.Rules[key]; !found {
+					t.Errorf("rule %s not found", key)
+				}
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	viper.Set("confdirs", []string{writeConfig(t, `presets:
+  appgroups:
+    none: {}
+  rules:
+    steam:
+      nice: 5
+      inherit: true
+      depth: 2
+    firefox:
+      nice: 1
+    mpv:
+      nice: -5
+`)})
+	pc, err := ReloadPresetCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// process tree, as pid, ppid, pgrp and comm
+	tree := func() []*Proc {
+		var procs []*Proc
+		for _, p := range []struct {
+			pid, ppid, pgrp int
+			comm            string
+		}{
+			{1, 0, 1, "systemd"},
+			{100, 1, 100, "steam"},
+			{101, 100, 100, "game"},
+			{102, 101, 100, "wine"},
+			{103, 102, 100, "wineserver"},
+			{104, 100, 104, "mpv"},
+			{200, 1, 200, "firefox"},
+			{201, 200, 200, "Web Content"},
+			{202, 201, 200, "gpu"},
+			{400, 1, 400, "bash"},
+			{401, 400, 400, "mpv"},
+			{402, 400, 400, "cat"},
+		} {
+			proc := newProc(p.pid, p.pgrp, p.comm, 0)
+			proc.Ppid = p.ppid
+			procs = append(procs, proc)
+		}
+		return procs
+	}
+	tests := []struct {
+		name    string
+		inherit bool
+		depth   int
+		group   bool
+		want    map[int]string // rule of the processes without their own
+	}{
+		{"rule option", false, 0, false, map[int]string{101: "steam", 102: "steam"}},
+		{"inherit", true, 0, false, map[int]string{101: "steam", 102: "steam", 201: "firefox", 202: "firefox"}},
+		{"inherit depth", true, 1, false, map[int]string{101: "steam", 102: "steam", 201: "firefox"}},
+		{"group", false, 0, true, map[int]string{101: "steam", 102: "steam", 103: "steam", 201: "firefox", 202: "firefox", 400: "mpv", 402: "mpv"}},
+	}
+	defer func(inherit bool, depth int, group bool) {
+		viper.Set("inherit", inherit)
+		viper.Set("depth", depth)
+		viper.Set("group", group)
+	}(viper.GetBool("inherit"), viper.GetInt("depth"), viper.GetBool("group"))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("inherit", tt.inherit)
+			viper.Set("depth", tt.depth)
+			viper.Set("group", tt.group)
+			procs := tree()
+			pc.Resolve(procs)
+			for _, p := range procs {
+				want, found := tt.want[p.Pid]
+				if !(found) {
+					want = p.Comm
+				}
+				if got := p.RuleName(); got != want {
+					t.Errorf("%s[%d]: got rule %q, want %q", p.Comm, p.Pid, got, want)
+				}
+			}
+		})
+	}
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
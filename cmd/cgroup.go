@@ -55,8 +55,11 @@ func (c *BaseCgroup) ScopeProperties() (result []string) {
 
 type Cgroup struct {
 	BaseCgroup `yaml:"basecgroup,omitempty,flow"`
-	CgroupKey  string `yaml:"cgroup,omitempty" json:"cgroup,omitempty"`
-	Origin     string `yaml:"origin,omitempty" json:"origin,omitempty"`
+	Variants   `yaml:"variants,omitempty"`
+	Budget     *Budget  `yaml:"budget,omitempty" json:"budget,omitempty"`
+	Reclaim    *Reclaim `yaml:"reclaim,omitempty" json:"reclaim,omitempty"`
+	CgroupKey  string   `yaml:"cgroup,omitempty" json:"cgroup,omitempty"`
+	Origin     string   `yaml:"origin,omitempty" json:"origin,omitempty"`
 }
 
 func (c Cgroup) Keys() (string, string) {
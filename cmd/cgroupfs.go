@@ -0,0 +1,332 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Pressure stall information
+// See https://docs.kernel.org/accounting/psi.html
+
+// PressureLine holds the share of time, in percent, during which some or all
+// tasks stalled on the resource.
+type PressureLine struct {
+	Avg10  float64 `yaml:"avg10" json:"avg10"`
+	Avg60  float64 `yaml:"avg60" json:"avg60"`
+	Avg300 float64 `yaml:"avg300" json:"avg300"`
+	Total  uint64  `yaml:"total" json:"total"` // in microseconds
+}
+
+// Pressure is the content of some pressure file. Full line is missing for
+// cpu resource on older kernels.
+type Pressure struct {
+	Some PressureLine `yaml:"some" json:"some"`
+	Full PressureLine `yaml:"full" json:"full"`
+}
+
+// Line returns the some or full line.
+func (p Pressure) Line(kind string) PressureLine {
+	if kind == "full" {
+		return p.Full
+	}
+	return p.Some
+}
+
+// UnmarshalText parses lines like
+// some avg10=0.00 avg60=0.00 avg300=0.00 total=0
+func (p *Pressure) UnmarshalText(data []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		var line *PressureLine
+		switch fields[0] {
+		case "some":
+			line = &p.Some
+		case "full":
+			line = &p.Full
+		default:
+			return fmt.Errorf("%w: pressure: %q", ErrParse, fields[0])
+		}
+		for _, field := range fields[1:] {
+			key, value, found := strings.Cut(field, "=")
+			if !(found) {
+				return fmt.Errorf("%w: pressure: %q", ErrParse, field)
+			}
+			var err error
+			switch key {
+			case "avg10":
+				line.Avg10, err = strconv.ParseFloat(value, 64)
+			case "avg60":
+				line.Avg60, err = strconv.ParseFloat(value, 64)
+			case "avg300":
+				line.Avg300, err = strconv.ParseFloat(value, 64)
+			case "total":
+				line.Total, err = strconv.ParseUint(value, 10, 64)
+			}
+			if err != nil {
+				return fmt.Errorf("%w: pressure: %v", ErrParse, err)
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// fsRoot returns the directory under which proc and sys filesystems are
+// found, from key or else fsroot key, for testing purpose.
+func fsRoot(key string) string {
+	for _, key := range []string{key, "fsroot"} {
+		if root := viper.GetString(key); root != "" {
+			return root
+		}
+	}
+	return "/"
+}
+
+// cgroupFile returns the path of the file name inside the cgroup at path,
+// relative to the cgroup filesystem found under cgroupfs.root key directory.
+func cgroupFile(path string, name string) string {
+	return filepath.Join(fsRoot("cgroupfs.root"), "sys", "fs", "cgroup", path, name)
+}
+
+// ReadPressure reads the pressure file at path, relative to pressure.root
+// key directory. That key only applies to pressure stall information files.
+func ReadPressure(path string) (p Pressure, err error) {
+	data, err := os.ReadFile(filepath.Join(fsRoot("pressure.root"), path))
+	if err != nil {
+		return
+	}
+	err = p.UnmarshalText(data)
+	return
+}
+
+// SystemPressure returns the system wide pressure on cpu, io or memory
+// resource.
+func SystemPressure(resource string) (Pressure, error) {
+	return ReadPressure(filepath.Join("proc", "pressure", resource))
+}
+
+// CgroupPressure returns the pressure on cpu, io or memory resource inside
+// the cgroup at path, relative to the cgroup filesystem.
+func CgroupPressure(path string, resource string) (Pressure, error) {
+	return ReadPressure(filepath.Join("sys", "fs", "cgroup", path, resource+".pressure"))
+}
+
+// CgroupCPUUsage returns the CPU time, in microseconds, used by the processes
+// inside the cgroup at path, relative to the cgroup filesystem.
+func CgroupCPUUsage(path string) (uint64, error) {
+	file := cgroupFile(path, "cpu.stat")
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) == 2 &&
+			fields[0] == "usage_usec" {
+			usage, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("%w: cpu.stat: %v", ErrParse, err)
+			}
+			return usage, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: cpu.stat: usage_usec", ErrParse)
+}
+
+// FreezeCgroup freezes or thaws the processes inside the cgroup at path,
+// relative to the cgroup filesystem.
+func FreezeCgroup(path string, frozen bool) error {
+	value := "0"
+	if frozen {
+		value = "1"
+	}
+	file := cgroupFile(path, "cgroup.freeze")
+	if err := os.WriteFile(file, []byte(value), 0644); err != nil {
+		return fmt.Errorf("%w: %v", ErrFailure, err)
+	}
+	return nil
+}
+
+// CgroupMemoryCurrent returns the memory, in bytes, used by the processes
+// inside the cgroup at path, relative to the cgroup filesystem.
+func CgroupMemoryCurrent(path string) (uint64, error) {
+	file := cgroupFile(path, "memory.current")
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	value, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: memory.current: %v", ErrParse, err)
+	}
+	return value, nil
+}
+
+// ReclaimCgroup asks the kernel to reclaim some bytes of memory from the
+// cgroup at path, relative to the cgroup filesystem. It fails when less was
+// reclaimed.
+func ReclaimCgroup(path string, bytes uint64) error {
+	file := cgroupFile(path, "memory.reclaim")
+	return os.WriteFile(file, []byte(strconv.FormatUint(bytes, 10)), 0644)
+}
+
+// CgroupFrozen returns whether the processes inside the cgroup at path,
+// relative to the cgroup filesystem, are frozen.
+func CgroupFrozen(path string) (bool, error) {
+	file := cgroupFile(path, "cgroup.events")
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return false, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) == 2 &&
+			fields[0] == "frozen" {
+			return fields[1] == "1", nil
+		}
+	}
+	return false, nil
+}
+
+// manageUserSlices is set when the nicy slices inside the service manager of
+// the other users are managed too, as root controlling their processes.
+var manageUserSlices bool
+
+// sliceUids returns the calling user, then the other users whose service
+// manager runs, when their nicy slices are managed too.
+func sliceUids() []int {
+	uids := []int{os.Getuid()}
+	if !(manageUserSlices) || uids[0] != 0 {
+		return uids
+	}
+	pattern := cgroupFile(filepath.Join("user.slice", "user-*.slice", "user@*.service"), "")
+	matches, _ := filepath.Glob(pattern)
+	var others []int
+	for _, match := range matches {
+		var uid int
+		if _, err := fmt.Sscanf(filepath.Base(match), "user@%d.service", &uid); err == nil && uid != 0 {
+			others = append(others, uid)
+		}
+	}
+	sort.Ints(others)
+	return append(uids, others...)
+}
+
+// SetSliceProperties sets at runtime the properties of the nicy slice for
+// cgroup key, inside the service manager of the calling user, then of the
+// other users when managed too.
+func SetSliceProperties(tag string, key string, properties []string) (err error) {
+	for _, uid := range sliceUids() {
+		if failure := setUserSliceProperties(tag, uid, key, properties); failure != nil && err == nil {
+			err = failure
+		}
+	}
+	return
+}
+
+// setUserSliceProperties sets at runtime the properties of the nicy slice for
+// cgroup key, inside the service manager of the user, or of the system for
+// root. Only root sets those of other users.
+func setUserSliceProperties(tag string, uid int, key string, properties []string) error {
+	if len(properties) == 0 {
+		return nil
+	}
+	var args []string
+	manager := "--system"
+	if uid != 0 {
+		manager = "--user"
+	}
+	if uid != os.Getuid() { // as the slice owner, like jobs do
+		u, err := GetUser(uid)
+		if err != nil {
+			return fmt.Errorf("%w: uid %d: %v", ErrNotFound, uid, err)
+		}
+		path := fmt.Sprintf("/run/user/%d", uid)
+		args = append(
+			args,
+			"runuser", "-u", u.Username, "--", "env",
+			fmt.Sprintf("DBUS_SESSION_BUS_ADDRESS=unix:path=%s/bus", path),
+			fmt.Sprintf("XDG_RUNTIME_DIR=%s", path),
+		)
+	}
+	unit := "nicy-" + key + ".slice"
+	args = append(args, "systemctl", manager, "--runtime", "set-property", unit)
+	c := NewCommand(append(args, properties...)...)
+	if err := c.StartWait(tag, &Streams{}); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrFailure, unit, err)
+	}
+	return nil
+}
+
+// nicySliceCgroup returns the path of the nicy slice for cgroup key, relative
+// to the cgroup filesystem, inside the service manager of the calling user.
+func nicySliceCgroup(key string) string {
+	return userSliceCgroup(os.Getuid(), key)
+}
+
+// userSliceCgroup returns the path of the nicy slice for cgroup key, relative
+// to the cgroup filesystem, inside the service manager of the user, or of the
+// system for root.
+func userSliceCgroup(uid int, key string) string {
+	slice := filepath.Join("nicy.slice", "nicy-"+key+".slice")
+	if uid != 0 {
+		return filepath.Join(
+			"user.slice", fmt.Sprintf("user-%d.slice", uid),
+			fmt.Sprintf("user@%d.service", uid), slice,
+		)
+	}
+	return slice
+}
+
+// nicySliceCgroups returns the paths of the nicy slices for cgroup key, inside
+// the service manager of the calling user, then of the other users when
+// managed too.
+func nicySliceCgroups(key string) (paths []string) {
+	for _, uid := range sliceUids() {
+		paths = append(paths, userSliceCgroup(uid, key))
+	}
+	return
+}
+
+// freezeNicySlices freezes or thaws the nicy slices for cgroup key, returning
+// the first error, if any. The slices of other users not running are skipped.
+func freezeNicySlices(key string, frozen bool) (err error) {
+	for i, path := range nicySliceCgroups(key) {
+		if i > 0 && !(exists(cgroupFile(path, ""))) {
+			continue
+		}
+		if failure := FreezeCgroup(path, frozen); failure != nil && err == nil {
+			err = failure
+		}
+	}
+	return
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
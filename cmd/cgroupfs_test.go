@@ -0,0 +1,94 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPressureUnmarshalText(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    Pressure
+		wantErr bool
+	}{
+		{
+			"some and full",
+			"some avg10=1.50 avg60=0.75 avg300=0.10 total=123456\n" +
+				"full avg10=0.50 avg60=0.25 avg300=0.00 total=789\n",
+			Pressure{
+				Some: PressureLine{Avg10: 1.5, Avg60: 0.75, Avg300: 0.1, Total: 123456},
+				Full: PressureLine{Avg10: 0.5, Avg60: 0.25, Total: 789},
+			},
+			false,
+		},
+		{
+			"some only, older kernel",
+			"some avg10=12.00 avg60=5.00 avg300=1.00 total=42\n",
+			Pressure{Some: PressureLine{Avg10: 12, Avg60: 5, Avg300: 1, Total: 42}},
+			false,
+		},
+		{
+			"empty lines",
+			"\nsome avg10=0.00 avg60=0.00 avg300=0.00 total=0\n\n",
+			Pressure{},
+			false,
+		},
+		{
+			"unknown key ignored",
+			"some avg10=3.00 avg30=9.00 total=1\n",
+			Pressure{Some: PressureLine{Avg10: 3, Total: 1}},
+			false,
+		},
+		{"empty", "", Pressure{}, false},
+		{"unknown line", "most avg10=1.00\n", Pressure{}, true},
+		{"missing value", "some avg10\n", Pressure{}, true},
+		{"bad float", "some avg10=high\n", Pressure{}, true},
+		{"negative total", "some total=-1\n", Pressure{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Pressure
+			err := got.UnmarshalText([]byte(tt.data))
+			if tt.wantErr {
+				if !(errors.Is(err, ErrParse)) {
+					t.Errorf("got %v, want ErrParse", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPressureLine(t *testing.T) {
+	p := Pressure{Some: PressureLine{Avg10: 1}, Full: PressureLine{Avg10: 2}}
+	for kind, want := range map[string]float64{"some": 1, "full": 2, "": 1} {
+		if got := p.Line(kind).Avg10; got != want {
+			t.Errorf("%q: got %v, want %v", kind, got, want)
+		}
+	}
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
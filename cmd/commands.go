@@ -67,6 +67,15 @@ func chrt(sched string, rtprio int, pid int) Command {
 	return NewCommand(append(tokens, "-a", "-p", strconv.Itoa(rtprio), sid)...)
 }
 
+// chrtThread is like chrt, for the thread tid only.
+func chrtThread(sched string, rtprio int, tid int) Command {
+	tokens := []string{"chrt"}
+	if len(sched) > 0 {
+		tokens = append(tokens, "--"+sched)
+	}
+	return NewCommand(append(tokens, "-p", strconv.Itoa(rtprio), strconv.Itoa(tid))...)
+}
+
 func ionice(class string, level int, pid int) Command {
 	// expects class in ["none", "realtime", "best-effort", "idle"]
 	// expects level in range [0..7] when it must be set
@@ -17,6 +17,7 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package cmd
 
 import (
+	"fmt"
 	"os"
 	"strings"
 
@@ -61,9 +62,21 @@ func (g *Group) ToProfile(key, origin string) Profile {
 	}
 }
 
+// AppCgroup is some cgroup, with its variants, CPU time budget and memory
+// reclaim policy.
+type AppCgroup struct {
+	BaseCgroup `yaml:",inline"`
+	Variants   `yaml:",inline"`
+	Budget     *Budget  `yaml:"budget,omitempty" json:"budget,omitempty"`
+	Reclaim    *Reclaim `yaml:"reclaim,omitempty" json:"reclaim,omitempty"`
+}
+
 type AppGroup struct {
 	Profile     Group    `yaml:"profile,omitempty,flow" json:"profile,omitempty"`
 	Assignments []string `yaml:"assignments,omitempty,flow" json:"assignments,omitempty"`
+	Inherit     bool     `yaml:"inherit,omitempty" json:"inherit,omitempty"`
+	Depth       int      `yaml:"depth,omitempty" json:"depth,omitempty"`
+	Variants    `yaml:",inline"`
 }
 
 type AppRule struct {
@@ -81,6 +94,10 @@ type AppRule struct {
 	MemoryMax   string            `yaml:"MemoryMax,omitempty" json:"MemoryMax,omitempty"`
 	CmdArgs     []string          `yaml:"cmdargs,omitempty,flow" json:"cmdargs,omitempty"`
 	Env         map[string]string `yaml:"env,omitempty,flow" json:"env,omitempty"`
+	Inherit     bool              `yaml:"inherit,omitempty" json:"inherit,omitempty"`
+	Depth       int               `yaml:"depth,omitempty" json:"depth,omitempty"`
+	Threads     ThreadProfiles    `yaml:"threads,omitempty" json:"threads,omitempty"`
+	Variants    `yaml:",inline"`
 }
 
 func (a AppRule) ToRule(key, origin string) Rule {
@@ -104,39 +121,43 @@ func (a AppRule) ToRule(key, origin string) Rule {
 			CgroupKey:  a.CgroupKey,
 			CmdArgs:    a.CmdArgs,
 			Env:        a.Env,
+			Inherit:    a.Inherit,
+			Depth:      a.Depth,
+			Threads:    a.Threads,
 		},
-		RuleKey: key,
-		Origin:  origin,
+		Variants: a.Variants,
+		RuleKey:  key,
+		Origin:   origin,
 	}
 }
 
 type Config struct {
 	Path      string
 	Origin    string
-	Cgroups   map[string]BaseCgroup `yaml:"cgroups,flow" json:"cgroups"`
-	AppGroups map[string]AppGroup   `yaml:"appgroups,flow" json:"appgroups"`
-	Rules     map[string]AppRule    `yaml:"rules,flow" json:"rules"`
+	Cgroups   map[string]AppCgroup `yaml:"cgroups,flow" json:"cgroups"`
+	AppGroups map[string]AppGroup  `yaml:"appgroups,flow" json:"appgroups"`
+	Rules     map[string]AppRule   `yaml:"rules,flow" json:"rules"`
 }
 
 func NewConfig(path string) (Config, error) {
-	var err error
 	cfg := struct {
 		Presets Config
 	}{
 		Presets: Config{
-			Cgroups:   make(map[string]BaseCgroup),
+			Cgroups:   make(map[string]AppCgroup),
 			AppGroups: make(map[string]AppGroup),
 			Rules:     make(map[string]AppRule),
 		},
 	}
-	if content, err := os.ReadFile(path); err == nil {
-		if err := yaml.Unmarshal([]byte(content), &cfg); err == nil {
-			cfg.Presets.SetOrigin(path)
-			return cfg.Presets, nil
-		}
+	content, err := os.ReadFile(path)
+	if err != nil {
 		return cfg.Presets, err
 	}
-	return cfg.Presets, err
+	if err = yaml.Unmarshal([]byte(content), &cfg); err != nil {
+		return cfg.Presets, fmt.Errorf("%w: %s: %v", ErrInvalid, path, err)
+	}
+	cfg.Presets.SetOrigin(path)
+	return cfg.Presets, nil
 }
 
 func (c *Config) SetOrigin(path string) {
@@ -157,7 +178,10 @@ func (c *Config) IterCgroups() chan Cgroup {
 	go func(ch chan Cgroup) {
 		for tag, cgroup := range c.Cgroups {
 			ch <- Cgroup{
-				BaseCgroup: cgroup,
+				BaseCgroup: cgroup.BaseCgroup,
+				Variants:   cgroup.Variants,
+				Budget:     cgroup.Budget,
+				Reclaim:    cgroup.Reclaim,
 				CgroupKey:  tag,
 				Origin:     c.Origin,
 			}
@@ -171,7 +195,9 @@ func (c *Config) IterProfiles() chan Profile {
 	ch := make(chan Profile)
 	go func(ch chan Profile) {
 		for tag, group := range c.AppGroups {
-			ch <- group.Profile.ToProfile(tag, c.Origin)
+			profile := group.Profile.ToProfile(tag, c.Origin)
+			profile.Variants = group.Variants
+			ch <- profile
 		}
 		close(ch)
 	}(ch)
@@ -200,6 +226,9 @@ func (c *Config) IterRules() chan Rule {
 						Origin:  c.Origin,
 					}
 				}
+				if group.Inherit && !(rule.Inherit) {
+					rule.Inherit, rule.Depth = true, group.Depth
+				}
 				tags[app] = tag
 				ch <- rule
 			}
@@ -0,0 +1,319 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+	"kernel.org/pub/linux/libs/security/libcap/cap"
+)
+
+// Process events connector
+// See linux/connector.h and linux/cn_proc.h
+
+const (
+	CN_IDX_PROC = 0x1
+	CN_VAL_PROC = 0x1
+)
+
+const (
+	PROC_CN_MCAST_LISTEN = iota + 1
+	PROC_CN_MCAST_IGNORE
+)
+
+const (
+	PROC_EVENT_NONE = 0x00000000
+	PROC_EVENT_FORK = 0x00000001
+	PROC_EVENT_EXEC = 0x00000002
+	PROC_EVENT_COMM = 0x00000200
+	PROC_EVENT_EXIT = 0x80000000
+)
+
+type cbId struct {
+	Idx uint32
+	Val uint32
+}
+
+type cnMsg struct {
+	Id    cbId
+	Seq   uint32
+	Ack   uint32
+	Len   uint16
+	Flags uint16
+}
+
+type procEventHeader struct {
+	What      uint32
+	CPU       uint32
+	Timestamp uint64
+}
+
+type forkProcEvent struct {
+	ParentPid  uint32
+	ParentTgid uint32
+	ChildPid   uint32
+	ChildTgid  uint32
+}
+
+type execProcEvent struct {
+	ProcessPid  uint32
+	ProcessTgid uint32
+}
+
+type commProcEvent struct {
+	ProcessPid  uint32
+	ProcessTgid uint32
+	Comm        [16]byte
+}
+
+type exitProcEvent struct {
+	ProcessPid  uint32
+	ProcessTgid uint32
+	ExitCode    uint32
+	ExitSignal  uint32
+}
+
+type cnMcastMsg struct {
+	Header unix.NlMsghdr
+	Msg    cnMsg
+	Op     uint32
+}
+
+const (
+	sizeofCnMsg           = int(unsafe.Sizeof(cnMsg{}))
+	sizeofProcEventHeader = int(unsafe.Sizeof(procEventHeader{}))
+)
+
+// rescanPid is sent instead of a pid when some events were lost.
+const rescanPid = 0
+
+// ProcEvent is about a forked, executed, renamed or exited process.
+type ProcEvent struct {
+	Pid  int
+	Exit bool
+}
+
+// ProcConnector listens to the process events that the kernel multicasts
+// through the NETLINK_CONNECTOR socket.
+type ProcConnector struct {
+	fd       int
+	received atomic.Int64 // when the socket was last read, in nanoseconds
+}
+
+// NewProcConnector opens the socket and subscribes to process events. It
+// requires CAP_NET_ADMIN capability.
+func NewProcConnector() (*ProcConnector, error) {
+	raiseCapability(cap.NET_ADMIN)
+	fd, err := unix.Socket(
+		unix.AF_NETLINK, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, unix.NETLINK_CONNECTOR,
+	)
+	if err != nil {
+		return nil, connectorError("socket", err)
+	}
+	addr := &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: CN_IDX_PROC}
+	if err = unix.Bind(fd, addr); err != nil {
+		unix.Close(fd)
+		return nil, connectorError("bind", err)
+	}
+	// do not block forever, in order to check context
+	tv := unix.NsecToTimeval(time.Second.Nanoseconds())
+	if err = unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv); err != nil {
+		unix.Close(fd)
+		return nil, connectorError("setsockopt", err)
+	}
+	pc := &ProcConnector{fd: fd}
+	if err = pc.send(PROC_CN_MCAST_LISTEN); err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+	return pc, nil
+}
+
+func connectorError(op string, err error) error {
+	if errors.Is(err, unix.EPERM) || errors.Is(err, unix.EACCES) {
+		return fmt.Errorf("%w: proc connector: %s: %v", ErrPermission, op, err)
+	}
+	return fmt.Errorf("%w: proc connector: %s: %v", ErrFailure, op, err)
+}
+
+func (pc *ProcConnector) send(op uint32) error {
+	msg := cnMcastMsg{
+		Header: unix.NlMsghdr{
+			Len:  uint32(unsafe.Sizeof(cnMcastMsg{})),
+			Type: unix.NLMSG_DONE,
+			Pid:  uint32(os.Getpid()),
+		},
+		Msg: cnMsg{
+			Id:  cbId{Idx: CN_IDX_PROC, Val: CN_VAL_PROC},
+			Len: uint16(unsafe.Sizeof(op)),
+		},
+		Op: op,
+	}
+	data := (*[unsafe.Sizeof(cnMcastMsg{})]byte)(unsafe.Pointer(&msg))[:]
+	dest := &unix.SockaddrNetlink{Family: unix.AF_NETLINK}
+	if err := unix.Sendto(pc.fd, data, 0, dest); err != nil {
+		return connectorError("send", err)
+	}
+	return nil
+}
+
+// Close unsubscribes and closes the socket.
+func (pc *ProcConnector) Close() error {
+	pc.send(PROC_CN_MCAST_IGNORE)
+	return unix.Close(pc.fd)
+}
+
+// parseProcEvent returns the event, if relevant. Events about threads are
+// discarded.
+func parseProcEvent(data []byte) (ev ProcEvent, ok bool) {
+	if len(data) < sizeofCnMsg+sizeofProcEventHeader {
+		return
+	}
+	header := (*procEventHeader)(unsafe.Pointer(&data[sizeofCnMsg]))
+	payload := data[sizeofCnMsg+sizeofProcEventHeader:]
+	switch header.What {
+	case PROC_EVENT_FORK:
+		if len(payload) >= int(unsafe.Sizeof(forkProcEvent{})) {
+			e := (*forkProcEvent)(unsafe.Pointer(&payload[0]))
+			return ProcEvent{Pid: int(e.ChildTgid)}, e.ChildPid == e.ChildTgid
+		}
+	case PROC_EVENT_EXEC:
+		if len(payload) >= int(unsafe.Sizeof(execProcEvent{})) {
+			e := (*execProcEvent)(unsafe.Pointer(&payload[0]))
+			return ProcEvent{Pid: int(e.ProcessTgid)}, true
+		}
+	case PROC_EVENT_COMM:
+		if len(payload) >= int(unsafe.Sizeof(commProcEvent{})) {
+			e := (*commProcEvent)(unsafe.Pointer(&payload[0]))
+			return ProcEvent{Pid: int(e.ProcessTgid)}, e.ProcessPid == e.ProcessTgid
+		}
+	case PROC_EVENT_EXIT:
+		if len(payload) >= int(unsafe.Sizeof(exitProcEvent{})) {
+			e := (*exitProcEvent)(unsafe.Pointer(&payload[0]))
+			return ProcEvent{Pid: int(e.ProcessTgid), Exit: true}, e.ProcessPid == e.ProcessTgid
+		}
+	}
+	return
+}
+
+// Events sends the events about processes, until the context is done. When
+// the socket buffer overruns, events are lost and an event about rescanPid
+// is sent instead.
+func (pc *ProcConnector) Events(ctx context.Context) <-chan ProcEvent {
+	ch := make(chan ProcEvent, 64)
+	go func() {
+		defer close(ch)
+		buf := make([]byte, os.Getpagesize())
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+			pc.received.Store(time.Now().UnixNano())
+			n, _, err := unix.Recvfrom(pc.fd, buf, 0)
+			switch {
+			case errors.Is(err, unix.EAGAIN), errors.Is(err, unix.EINTR):
+				continue
+			case errors.Is(err, unix.ENOBUFS):
+				select {
+				case ch <- ProcEvent{Pid: rescanPid}:
+				case <-ctx.Done():
+					return
+				}
+				continue
+			case err != nil:
+				nonfatal(connectorError("receive", err))
+				return
+			}
+			msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+			if err != nil {
+				continue
+			}
+			for _, msg := range msgs {
+				if msg.Header.Type != unix.NLMSG_DONE {
+					continue
+				}
+				if ev, ok := parseProcEvent(msg.Data); ok {
+					select {
+					case ch <- ev:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+	return ch
+}
+
+// Alive returns whether the events were read from the socket within d. The
+// socket is read at least every second, while no event comes.
+func (pc *ProcConnector) Alive(d time.Duration) bool {
+	return time.Since(time.Unix(0, pc.received.Load())) < d
+}
+
+// Batch collects the pids received during delay, removing duplicates.
+func Batch(ctx context.Context, pids <-chan int, delay time.Duration) <-chan []int {
+	ch := make(chan []int, 8)
+	go func() {
+		defer close(ch)
+		var (
+			batch []int
+			seen  = make(map[int]bool)
+			timer = time.NewTimer(delay)
+		)
+		timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case pid, ok := <-pids:
+				if !ok {
+					return
+				}
+				if seen[pid] {
+					continue
+				}
+				if len(batch) == 0 {
+					timer.Reset(delay)
+				}
+				seen[pid] = true
+				batch = append(batch, pid)
+			case <-timer.C:
+				select {
+				case ch <- batch:
+				case <-ctx.Done():
+					return
+				}
+				batch = nil
+				seen = make(map[int]bool)
+			}
+		}
+	}()
+	return ch
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
@@ -0,0 +1,96 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// eventData returns the data of some netlink message carrying the event of
+// type what, with payload ev.
+func eventData[T any](what uint32, ev T) []byte {
+	data := make([]byte, sizeofCnMsg+sizeofProcEventHeader+int(unsafe.Sizeof(ev)))
+	header := (*procEventHeader)(unsafe.Pointer(&data[sizeofCnMsg]))
+	header.What = what
+	*(*T)(unsafe.Pointer(&data[sizeofCnMsg+sizeofProcEventHeader])) = ev
+	return data
+}
+
+func TestParseProcEvent(t *testing.T) {
+	fork := forkProcEvent{ParentPid: 1, ParentTgid: 1, ChildPid: 42, ChildTgid: 42}
+	thread := forkProcEvent{ParentPid: 42, ParentTgid: 42, ChildPid: 43, ChildTgid: 42}
+	comm := commProcEvent{ProcessPid: 42, ProcessTgid: 42}
+	copy(comm.Comm[:], "firefox")
+	tests := []struct {
+		name   string
+		data   []byte
+		want   ProcEvent
+		wantOk bool
+	}{
+		{"fork", eventData(PROC_EVENT_FORK, fork), ProcEvent{Pid: 42}, true},
+		{"fork thread", eventData(PROC_EVENT_FORK, thread), ProcEvent{Pid: 42}, false},
+		{"exec", eventData(PROC_EVENT_EXEC, execProcEvent{43, 42}), ProcEvent{Pid: 42}, true},
+		{"comm", eventData(PROC_EVENT_COMM, comm), ProcEvent{Pid: 42}, true},
+		{"comm thread", eventData(PROC_EVENT_COMM, commProcEvent{ProcessPid: 43, ProcessTgid: 42}), ProcEvent{Pid: 42}, false},
+		{"exit", eventData(PROC_EVENT_EXIT, exitProcEvent{ProcessPid: 42, ProcessTgid: 42}), ProcEvent{Pid: 42, Exit: true}, true},
+		{"exit thread", eventData(PROC_EVENT_EXIT, exitProcEvent{ProcessPid: 43, ProcessTgid: 42}), ProcEvent{Pid: 42, Exit: true}, false},
+		{"none", eventData(PROC_EVENT_NONE, execProcEvent{42, 42}), ProcEvent{}, false},
+		{"truncated payload", eventData(PROC_EVENT_FORK, fork)[:sizeofCnMsg+sizeofProcEventHeader+4], ProcEvent{}, false},
+		{"truncated header", eventData(PROC_EVENT_EXEC, execProcEvent{42, 42})[:sizeofCnMsg+4], ProcEvent{}, false},
+		{"empty", nil, ProcEvent{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ev, ok := parseProcEvent(tt.data)
+			if ev != tt.want || ok != tt.wantOk {
+				t.Errorf("got (%+v, %v), want (%+v, %v)", ev, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestBatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pids := make(chan int)
+	batches := Batch(ctx, pids, 50*time.Millisecond)
+	for _, pid := range []int{42, 43, 42, rescanPid, 43} {
+		pids <- pid
+	}
+	select {
+	case got := <-batches:
+		if want := []int{42, 43, rescanPid}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no batch sent")
+	}
+	pids <- 42
+	if got := <-batches; !reflect.DeepEqual(got, []int{42}) {
+		t.Errorf("got %v after first batch, want [42]", got)
+	}
+	close(pids)
+	if _, ok := <-batches; ok {
+		t.Error("batches not closed with pids")
+	}
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
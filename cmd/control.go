@@ -21,6 +21,9 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -30,19 +33,57 @@ import (
 
 // controlCmd represents the control command
 var controlCmd = &cobra.Command{
-	Use:   "control [-n] [-u|-g|-s|-a] [-t SECONDS]",
+	Use:   "control [-n] [-u|-g|-s|-a] [-t SECONDS] [--min-age AGE] [--metrics ADDRESS] [--metrics-file FILE] [--status|--stop]",
 	Short: "Control running processes",
 	Long: `Control the running processes, applying rules, if any
 
 The processes are selected when their group leader matches an existing rule.
+New processes are detected as soon as they are forked, executed or renamed,
+when the kernel process events connector is available. Otherwise, all the
+running processes are scanned every --tick interval.
+Processes that already match their rule are not reviewed again, while they run.
+The presets are reloaded from the configuration files when they change or when
+SIGHUP or SIGUSR2 is received. Invalid presets are reported and the previous
+ones are kept.
+The running control command can be queried and driven with the ctl command,
+through its socket.
+Metrics in the Prometheus text format can be served over HTTP on the --metrics
+address, either unix socket path or TCP host:port, or written into the
+--metrics-file file for some textfile collector.
+Attributes changed by hand after being applied are not enforced anymore, until
+the process exits. See pin and unpin commands.
+With --foreground option, the nice and I/O attributes of the profile apply to
+the foreground process group of each terminal, with or without rule, until put
+in the background, suspended or traced.
+The nicy slices are throttled when under pressure, as set in pressure steps.
+The CPU time used inside the nicy slices of cgroups with some budget is
+tracked, and the slices are clamped or frozen once the budget is used up, until
+the budget window resets.
+The processes moved to some realtime class by their rule are demoted to the
+other class when using more CPU time than the watchdog share, and not promoted
+again until the cooldown ends.
+The memory of the nicy slices of cgroups with some reclaim policy is reclaimed
+once they stay idle long enough.
+All processes are reviewed again when the power source changes, or when some
+schedule window opens or closes, with the active variants of the presets, and
+the nicy slices updated.
+Failing process groups are retried later, twice as late after each failure,
+until given up after some failures.
+Only one control command runs per scope and user. The --status option shows
+whether it runs and the --stop option stops it.
 The --user option is the implied default, when none is given.
-Only superuser can fully run manage command with --system, --global or --all option.`,
+Only superuser can fully run manage command with --system, --global or --all option.
+With --global or --all option, processes inside user slices are managed with
+the presets of their owner.`,
 	Args:                  cobra.MaximumNArgs(0),
 	DisableFlagsInUseLine: true,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
 		fs := cmd.LocalNonPersistentFlags()
 		// Bind shared flags
 		err := viper.BindPFlags(fs)
+		if err == nil {
+			err = checkLiveProcFS()
+		}
 		if tick := viper.GetDuration("tick"); tick.Seconds() < 2 || tick.Seconds() > 3600 {
 			msg := fmt.Sprintf("must range from 2s to 1h, got %v", tick)
 			return fmt.Errorf("%w: %s", ErrParse, msg)
@@ -54,18 +95,30 @@ Only superuser can fully run manage command with --system, --global or --all opt
 		// Debug output
 		debugOutput(cmd)
 		// Real job goes here
-		presetCache = GetPresetCache() // get cache, once for all goroutines
 		scope := GetStringFromFlags("user", viper.GetStringSlice("scopes")...)
+		path := controlPidFile(scope)
+		switch {
+		case viper.GetBool("status"):
+			pid, err := RunningPid(path)
+			fatalIs(err, ErrNotFound)
+			fmt.Fprintf(cmd.OutOrStdout(), "running with pid %d\n", pid)
+			return
+		case viper.GetBool("stop"):
+			fatalIs(stopControl(path), ErrNotFound)
+			return
+		}
+		presetCache = GetPresetCache() // get cache, once for all goroutines
+		pidfile, err := AcquirePidFile(path)
+		fatalIs(err, ErrAlready)
 		if err := setCapabilities(true); err != nil {
 			cmd.PrintErrln(err)
 		}
-		defer func() {
-			if err := setCapabilities(false); err != nil {
-				cmd.PrintErrln(err)
-			}
-		}()
 		filter := GetScopeOnlyFilterer(scope)
-		err := doControlCmd("", filter, &Streams{Stdin: nil, Stdout: cmd.OutOrStdout(), Stderr: cmd.ErrOrStderr()})
+		err = doControlCmd("", filter, &Streams{Stdin: nil, Stdout: cmd.OutOrStdout(), Stderr: cmd.ErrOrStderr()})
+		if err := setCapabilities(false); err != nil {
+			cmd.PrintErrln(err)
+		}
+		pidfile.Release() // before exiting on error
 		fatal(wrap(err))
 	},
 }
@@ -78,93 +131,640 @@ func init() {
 	fs.SetInterspersed(false)
 	viper.Set("scopes", addScopeFlags(controlCmd))
 	addDryRunFlag(controlCmd)
-	fs.DurationP("tick", "t", 5*time.Second, "delay between consecutive runs in seconds")
+	addMatchFlags(controlCmd)
+	fs.DurationP("tick", "t", 5*time.Second, "delay between consecutive scans, without process events")
+	fs.Duration("min-age", 0, "ignore processes younger than `AGE`")
+	fs.String("foreground", "", "boost foreground process groups of terminals with `PROFILE`")
+	fs.Bool("status", false, "show whether control command runs")
+	fs.Bool("stop", false, "stop running control command")
+	controlCmd.MarkFlagsMutuallyExclusive("status", "stop")
+	fs.String("metrics", "", "serve metrics on unix socket or TCP `ADDRESS`")
+	fs.String("metrics-file", "", "write metrics into `FILE` every tick")
 	controlCmd.InheritedFlags().SortFlags = false
 }
 
+// stopControl signals the running control command to stop and waits until it
+// releases its pid file.
+func stopControl(path string) error {
+	pid, err := RunningPid(path)
+	if err != nil {
+		return err
+	}
+	if err = unix.Kill(pid, unix.SIGTERM); err != nil {
+		return fmt.Errorf("%w: pid %d: %v", ErrPermission, pid, err)
+	}
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); {
+		if _, err = RunningPid(path); err != nil {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return fmt.Errorf("%w: pid %d still running", ErrFailure, pid)
+}
+
+// eventDelay is the time spent collecting process events before handling them.
+const eventDelay = 100 * time.Millisecond
+
+// reloadDelay is the time without file change before reloading the presets.
+const reloadDelay = 500 * time.Millisecond
+
+// controlCaches are the caches used by control command, swapped on reload.
+var controlCaches atomic.Pointer[UserCaches]
+
+// controlPaused is set when control command must not touch any process.
+var controlPaused atomic.Bool
+
+// controlAdjusted counts the process groups adjusted by control command.
+var controlAdjusted atomic.Int64
+
+// ControlStatus describes the running control command.
+type ControlStatus struct {
+	Pid       int       `yaml:"pid" json:"pid"`
+	Scope     string    `yaml:"scope" json:"scope"`
+	Started   time.Time `yaml:"started" json:"started"`
+	Events    bool      `yaml:"events" json:"events"`
+	Tick      string    `yaml:"tick" json:"tick"`
+	Paused    bool      `yaml:"paused" json:"paused"`
+	DryRun    bool      `yaml:"dry_run" json:"dry_run"`
+	CacheDate string    `yaml:"cache_date" json:"cache_date"`
+	Processes int       `yaml:"processes" json:"processes"`
+	Groups    int       `yaml:"groups" json:"groups"`
+	Power     string    `yaml:"power" json:"power"`
+	Throttled []string  `yaml:"throttled,omitempty" json:"throttled,omitempty"`
+	Exhausted []string  `yaml:"exhausted,omitempty" json:"exhausted,omitempty"`
+	Demoted   []string  `yaml:"demoted,omitempty" json:"demoted,omitempty"`
+}
+
+// controlStatusLine sums up what control command manages.
+func controlStatusLine() string {
+	line := fmt.Sprintf(
+		"%d processes in %d groups, %d adjustments",
+		procState.Len(), len(procState.Groups()), controlAdjusted.Load(),
+	)
+	if controlPaused.Load() {
+		line += " (paused)"
+	}
+	return line
+}
+
+// reloadControlCache swaps the caches, the pressure steps and the watchdog
+// limit, only when the presets, the steps and the limit are all valid.
+func reloadControlCache(throttler *Throttler, watchdog *Watchdog) error {
+	pc, err := ReloadPresetCache()
+	if err != nil {
+		return err
+	}
+	v, err := reloadConfig()
+	if err != nil {
+		return err
+	}
+	next, err := newThrottler(pc, v)
+	if err != nil {
+		return err
+	}
+	limit, err := newWatchdog(v)
+	if err != nil {
+		return err
+	}
+	throttler.Replace(pc, next)
+	watchdog.Replace(limit)
+	controlCaches.Store(NewUserCaches(pc, controlCaches.Load().PerUser))
+	procState.Reset() // review all processes with new presets
+	return nil
+}
+
+// controller holds what the goroutines of control command share.
+type controller struct {
+	tag       string
+	std       *Streams
+	filter    ProcFilterer
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	runjobs   chan *ProcGroupJob
+	procs     chan []*Proc  // some nil value probes the pipeline for watchdog
+	probed    chan struct{} // probe went through
+	pids      chan int      // processes to review
+	connector *ProcConnector
+	events    bool // process events are read
+	started   time.Time
+	throttler *Throttler
+	budgets   *Budgets
+	reclaimer *Reclaimer
+	watchdog  *Watchdog
+	mu        sync.Mutex // serializes the selection of processes to review
+}
+
 func doControlCmd(tag string, filter ProcFilterer, std *Streams) (err error) {
-	// prepare channels
-	runjobs := make(chan *ProcGroupJob, 8)
-	procs := make(chan []*Proc, 8)
+	if name := viper.GetString("foreground"); name != "" && !(presetCache.HasPreset("profile", name)) {
+		return fmt.Errorf("%w: foreground profile %s", ErrNotFound, name)
+	}
+	c := &controller{
+		tag:       tag,
+		std:       std,
+		filter:    filter,
+		runjobs:   make(chan *ProcGroupJob, 8),
+		procs:     make(chan []*Proc, 8),
+		probed:    make(chan struct{}, 1),
+		pids:      make(chan int, 64),
+		reclaimer: NewReclaimer(),
+		started:   time.Now(),
+	}
+	if c.throttler, err = NewThrottler(&presetCache); err != nil {
+		return err
+	}
+	if c.watchdog, err = NewWatchdog(); err != nil {
+		return err
+	}
+	if c.budgets, err = NewBudgets(budgetFile()); err != nil {
+		nonfatal(fmt.Errorf("%w: resetting budgets", err))
+	}
 	// and signal
-	ctx := context.Background()
-	ctx, cancel := context.WithCancel(ctx)
+	c.ctx, c.cancel = context.WithCancel(context.Background())
 	signalChan := make(chan os.Signal, 1)
-	signal.Notify(signalChan, os.Interrupt, unix.SIGUSR1, unix.SIGUSR2)
-	ticker := time.NewTicker(viper.GetDuration("tick"))
+	signal.Notify(signalChan, os.Interrupt, unix.SIGTERM, unix.SIGHUP, unix.SIGUSR1, unix.SIGUSR2)
 	defer func() {
 		signal.Stop(signalChan)
-		ticker.Stop()
-		cancel()
+		c.cancel()
 	}()
-	// spin up workers
-	wg := getWaitGroup() // use a sync.WaitGroup to indicate completion
+	c.runWorkers()
+	pc := presetCache
+	controlCaches.Store(NewUserCaches(&pc, perUserScope(filter)))
+	manageUserSlices = perUserScope(filter)
+	c.runReviewer()
+	// send input
+	if viper.GetBool("dry-run") || viper.GetBool("verbose") {
+		inform("", fmt.Sprintf("Setting %v every %v...", filter, viper.GetDuration("tick")))
+	}
+	procState = NewProcState(viper.GetDuration("min-age"))
+	writeMetrics := c.startMetrics()
+	defer writeMetrics()
+	// wait for process events, if possible, or poll
+	err = fmt.Errorf("%w: %s: not live", ErrNotFound, procFS.Root)
+	if procFS.Live() { // recorded processes raise no event
+		c.connector, err = NewProcConnector()
+	}
+	c.events = err == nil
+	c.scan()
+	nonfatal(sdNotify("READY=1", "STATUS="+controlStatusLine()))
+	if c.events {
+		defer c.connector.Close()
+		c.listenEvents()
+	} else if viper.GetBool("dry-run") || viper.GetBool("verbose") {
+		inform("warning", fmt.Sprintf("%v: polling every %v", err, viper.GetDuration("tick")))
+	}
+	// foreground process groups of terminals change without any event
+	if !(c.events) || viper.GetString("foreground") != "" {
+		c.poll()
+	}
+	defer func() {
+		c.throttler.Revert(controlCaches.Load().Base)
+		c.budgets.Revert(controlCaches.Load().Base)
+	}()
+	c.watchSlices()
+	c.watchVariants()
+	// reload presets when configuration files change
+	changes, err := WatchDirs(c.ctx, viper.GetStringSlice("confdirs"), reloadDelay)
+	if err != nil {
+		debug(err)
+	}
+	// listen to requests
+	scope := "user"
+	if pf, ok := filter.(ProcFilter); ok {
+		scope = pf.Scope
+	}
+	if server, err := NewCtlServer(controlSocket(scope)); err == nil {
+		defer server.Close()
+		c.handle(server)
+		go server.Serve(c.ctx)
+	} else {
+		nonfatal(err)
+	}
+	// keep service manager informed
+	status := time.NewTicker(viper.GetDuration("tick"))
+	defer status.Stop()
+	// ping only once some probe goes through the reviewer and some worker,
+	// while process events are read, without any additional scan
+	var watchdog <-chan time.Time
+	interval := sdWatchdog()
+	if interval > 0 {
+		ping := time.NewTicker(interval / 2)
+		defer ping.Stop()
+		watchdog = ping.C
+	}
+	probing := false
+	for {
+		select {
+		case <-watchdog:
+			if probing { // previous cycle not completed yet
+				continue
+			}
+			probing = true
+			go func() {
+				select {
+				case <-c.ctx.Done():
+				case c.procs <- nil:
+				}
+			}()
+		case <-c.probed:
+			probing = false
+			if c.events && !(c.connector.Alive(interval)) {
+				continue
+			}
+			nonfatal(sdNotify("WATCHDOG=1"))
+		case <-status.C:
+			nonfatal(sdNotify("STATUS=" + controlStatusLine()))
+			writeMetrics()
+			if given := procState.GivenUp(); len(given) > 0 {
+				inform("warning", fmt.Sprintf(
+					"giving up after %d failures on %d processes: %s",
+					maxFailures, len(given), strings.Join(given, ", "),
+				))
+			}
+		case path, ok := <-changes:
+			if !ok {
+				changes = nil
+				continue
+			}
+			c.reload(path)
+		case s := <-signalChan:
+			switch s {
+			case unix.SIGUSR1, unix.SIGTERM, os.Interrupt:
+				nonfatal(sdNotify("STOPPING=1"))
+				c.cancel()
+				// break
+			case unix.SIGHUP, unix.SIGUSR2:
+				c.reload(s.String())
+			}
+		case <-c.ctx.Done():
+			if viper.GetBool("dry-run") || viper.GetBool("verbose") {
+				inform("", "Done.")
+			}
+			return nil
+		}
+	}
+}
+
+// backoff reports failures once, then on verbose only.
+func (c *controller) backoff(job *ProcGroupJob, err error) {
+	failures, retry := procState.Fail(job, err)
+	switch {
+	case failures == 1:
+		warn(err)
+	case viper.GetBool("verbose"):
+		inform("", fmt.Sprintf("%v (failure %d/%d)", err, failures, maxFailures))
+	}
+	if failures < maxFailures && viper.GetBool("verbose") {
+		inform("", fmt.Sprintf("pgrp %d: retrying in %v", job.Pgrp, retry))
+	}
+}
+
+// runWorkers spins up the workers running the jobs.
+func (c *controller) runWorkers() {
 	for i := 0; i < (goMaxProcs + 1); i++ {
-		wg.Add(1) // run jobs
+		c.wg.Add(1)
 		go func(id int) {
-			defer wg.Done()
+			defer c.wg.Done()
 			select {
-			case <-ctx.Done():
+			case <-c.ctx.Done():
 				if viper.GetBool("dry-run") || viper.GetBool("verbose") {
 					inform("", fmt.Sprintf("Goroutine #%d cancelled.", id))
 				}
 				return
 			default:
-				for job := range runjobs {
-					err = job.Run(tag, std)
-					if err != nil {
-						return
+				for job := range c.runjobs {
+					if job == nil { // watchdog probe went through
+						select {
+						case c.probed <- struct{}{}:
+						default:
+						}
+						continue
+					}
+					if err := job.Run(c.tag, c.std); err != nil {
+						c.backoff(job, err)
+						continue
+					}
+					procState.Succeed(job)
+					if !(viper.GetBool("dry-run")) {
+						procState.Apply(job)
+						controlAdjusted.Add(1)
 					}
 				}
 			}
 		}(i)
 	}
-	wg.Add(1) // get jobs
-	go presetCache.GenerateGroupJobs(procs, runjobs, &wg)
-	// send input
+}
+
+// runReviewer gets the jobs of the processes to review, with the current
+// cache.
+func (c *controller) runReviewer() {
+	c.wg.Add(1)
+	go func() {
+		defer func() {
+			close(c.runjobs)
+			c.wg.Done()
+		}()
+		for batch := range c.procs {
+			if batch == nil {
+				c.runjobs <- nil
+				continue
+			}
+			controlCaches.Load().SendGroupJobs(batch, c.runjobs)
+		}
+	}()
+}
+
+// startMetrics prepares the metrics, if required, and returns the function
+// writing them into the metrics file, if any.
+func (c *controller) startMetrics() func() {
+	if viper.GetString("metrics") != "" || viper.GetString("metrics-file") != "" {
+		controlMetrics = NewMetrics()
+		controlMetrics.Diverging = func() (count int) {
+			for _, status := range procState.Groups() {
+				if !(status.Settled) {
+					count++
+				}
+			}
+			return
+		}
+	}
+	if address := viper.GetString("metrics"); address != "" {
+		nonfatal(controlMetrics.Serve(c.ctx, address))
+	}
+	return func() {
+		if path := viper.GetString("metrics-file"); path != "" {
+			nonfatal(controlMetrics.WriteFile(path))
+		}
+	}
+}
+
+// later reviews again the process after delay, when process events are read,
+// otherwise on next tick.
+func (c *controller) later(pid int, delay time.Duration) {
+	if !(c.events) {
+		return
+	}
+	time.AfterFunc(delay, func() {
+		select {
+		case <-c.ctx.Done():
+		case c.pids <- pid:
+		}
+	})
+}
+
+// send selects, among found processes, those to review and sends them, one
+// selection at a time.
+func (c *controller) send(found []*Proc, prune bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if prune {
+		procState.Prune(found)
+	}
+	ready, deferred := procState.Select(found)
+	for _, p := range deferred { // review when old enough or retry
+		c.later(p.Pid, procState.Wait(p))
+	}
+	if len(ready) > 0 {
+		select {
+		case <-c.ctx.Done():
+		case c.procs <- ready:
+		}
+	}
+}
+
+// scan reviews all the running processes, unless paused.
+func (c *controller) scan() {
+	if controlPaused.Load() {
+		return
+	}
+	start := time.Now()
+	found := FilteredProcs(c.filter)
+	controlMetrics.Scan(time.Since(start))
+	c.send(found, true)
+}
+
+// listenEvents reviews the processes as soon as they are forked, executed or
+// renamed, and forgets them once they exit.
+func (c *controller) listenEvents() {
 	if viper.GetBool("dry-run") || viper.GetBool("verbose") {
-		inform("", fmt.Sprintf("Setting %v every %v...", filter, viper.GetDuration("tick")))
+		inform("", "Listening to process events...")
 	}
-	procs <- FilteredProcs(filter)
-	wg.Add(1)
+	c.wg.Add(1)
 	go func() {
-		defer wg.Done()
+		defer c.wg.Done()
+		for ev := range c.connector.Events(c.ctx) {
+			if ev.Exit {
+				procState.Forget(ev.Pid)
+				continue
+			}
+			select {
+			case <-c.ctx.Done():
+				return
+			case c.pids <- ev.Pid:
+			}
+		}
+	}()
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		for batch := range Batch(c.ctx, c.pids, eventDelay) {
+			if Contains(batch, rescanPid) { // some events were lost
+				c.scan()
+				continue
+			}
+			if controlPaused.Load() {
+				continue
+			}
+			c.send(SelectedProcs(batch, c.filter), false)
+		}
+	}()
+}
+
+// poll scans the running processes every tick.
+func (c *controller) poll() {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		ticker := time.NewTicker(viper.GetDuration("tick"))
+		defer ticker.Stop()
 		for {
 			select {
-			case <-ctx.Done():
+			case <-c.ctx.Done():
 				return
 			case <-ticker.C:
-				procs <- FilteredProcs(filter)
+				c.scan()
 			}
 		}
 	}()
-	for {
-		select {
-		case s := <-signalChan:
-			switch s {
-			case unix.SIGUSR1:
-				cancel()
-				// break
-			case unix.SIGUSR2:
-				// TODO: Reload cache here
-				if viper.GetBool("dry-run") || viper.GetBool("verbose") {
-					inform("", "Reloading cache...")
-					presetCache.LoadFromConfig()
-					inform("", "Cache reloaded.")
+}
+
+// watchSlices throttles the nicy slices under pressure, clamps those out of
+// budget, reclaims the memory of the idle ones, and demotes the realtime
+// processes using too much CPU time.
+func (c *controller) watchSlices() {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		ticker := time.NewTicker(viper.GetDuration("tick"))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.ctx.Done():
+				return
+			case now := <-ticker.C:
+				pc := controlCaches.Load().Base
+				for _, key := range c.throttler.Update(pc, now) {
+					c.budgets.Reapply(pc, key)
+				}
+				for _, key := range c.budgets.Update(pc, now) {
+					c.throttler.Reapply(pc, key)
+				}
+				c.reclaimer.Update(pc, now)
+				if c.watchdog.Update(now, c.tag, c.std) {
+					c.scan() // promote again after cooldown
 				}
-			case os.Interrupt:
-				cancel()
-				os.Exit(1)
 			}
-		case <-ctx.Done():
-			if viper.GetBool("dry-run") || viper.GetBool("verbose") {
-				inform("", "Done.")
+		}
+	}()
+}
+
+// watchVariants reviews all the processes when the power source changes, or
+// when some schedule window opens or closes.
+func (c *controller) watchVariants() {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		ticker := time.NewTicker(viper.GetDuration("tick"))
+		defer ticker.Stop()
+		windows := controlCaches.Load().Base.OpenWindows(time.Now())
+		for {
+			select {
+			case <-c.ctx.Done():
+				return
+			case now := <-ticker.C:
+				pc := controlCaches.Load().Base
+				source, changed := UpdatePowerSource()
+				if changed && (viper.GetBool("dry-run") || viper.GetBool("verbose")) {
+					inform("", fmt.Sprintf("Power source changed to %s.", source))
+				}
+				if open := pc.OpenWindows(now); open != windows {
+					windows, changed = open, true
+					if viper.GetBool("dry-run") || viper.GetBool("verbose") {
+						inform("", "Schedule windows changed.")
+					}
+				}
+				if !(changed) {
+					continue
+				}
+				for key, properties := range pc.VariantSliceProperties() {
+					nonfatal(SetSliceProperties("variant", key, properties))
+					c.throttler.Reapply(pc, key)
+					c.budgets.Reapply(pc, key)
+				}
+				procState.Reset()
+				c.scan()
 			}
-			return nil
 		}
+	}()
+}
+
+// reload reloads the presets, keeping the previous ones when invalid, and
+// reviews all the processes again.
+func (c *controller) reload(reason string) error {
+	if viper.GetBool("dry-run") || viper.GetBool("verbose") {
+		inform("", fmt.Sprintf("Reloading presets (%s)...", reason))
+	}
+	nonfatal(sdNotify(sdReloading()...))
+	defer func() {
+		nonfatal(sdNotify("READY=1", "STATUS="+controlStatusLine()))
+	}()
+	if err := reloadControlCache(c.throttler, c.watchdog); err != nil {
+		nonfatal(fmt.Errorf("%w: keeping previous presets", err))
+		return err
+	}
+	if viper.GetBool("dry-run") || viper.GetBool("verbose") {
+		inform("", "Presets reloaded.")
+	}
+	c.scan()
+	return nil
+}
+
+// handle registers the handlers of the requests to the control socket.
+func (c *controller) handle(server *CtlServer) {
+	server.Handle("status", func(req CtlRequest) (any, error) {
+		return ControlStatus{
+			Pid:       os.Getpid(),
+			Scope:     c.filter.String(),
+			Started:   c.started,
+			Events:    c.events,
+			Tick:      viper.GetDuration("tick").String(),
+			Paused:    controlPaused.Load(),
+			DryRun:    viper.GetBool("dry-run"),
+			CacheDate: controlCaches.Load().Base.Date,
+			Processes: procState.Len(),
+			Groups:    len(procState.Groups()),
+			Power:     CurrentPowerSource(),
+			Throttled: c.throttler.Throttled(),
+			Exhausted: c.budgets.Exhausted(),
+			Demoted:   procState.Demotions(),
+		}, nil
+	})
+	server.Handle("reload", func(req CtlRequest) (any, error) {
+		return nil, c.reload("request")
+	})
+	server.Handle("pause", func(req CtlRequest) (any, error) {
+		controlPaused.Store(true)
+		return nil, nil
+	})
+	server.Handle("resume", func(req CtlRequest) (any, error) {
+		if controlPaused.Swap(false) {
+			procState.Reset() // review all processes again
+			c.scan()
+		}
+		return nil, nil
+	})
+	server.Handle("list", func(req CtlRequest) (any, error) {
+		return procState.Groups(), nil
+	})
+	server.Handle("apply", func(req CtlRequest) (any, error) {
+		if req.Pid <= 0 {
+			return nil, fmt.Errorf("%w: pid required", ErrInvalid)
+		}
+		job, err := controlCaches.Load().ApplyPreset(req.Pid, req.Preset, c.tag, c.std)
+		if err != nil {
+			return nil, err
+		}
+		return GroupStatus{
+			Pgrp:    job.Pgrp,
+			Comm:    job.leader.Proc.Comm,
+			Pids:    job.Pids,
+			Settled: true,
+			Diff:    ToInterface(job.Diff),
+		}, nil
+	})
+	pin := func(req CtlRequest, pinned bool) (any, error) {
+		found := SelectedProcs([]int{req.Pid}, GetFilterer("all"))
+		if len(found) == 0 {
+			return nil, fmt.Errorf("%w: process %d", ErrNotFound, req.Pid)
+		}
+		if pinned {
+			procState.Pin(found[0])
+			return nil, nil
+		}
+		procState.Unpin(found[0])
+		c.scan()
+		return nil, nil
 	}
-	// wg.Wait() // wait on the workers to finish
-	// return
+	server.Handle("pin", func(req CtlRequest) (any, error) {
+		return pin(req, true)
+	})
+	server.Handle("unpin", func(req CtlRequest) (any, error) {
+		return pin(req, false)
+	})
+	server.Handle("shutdown", func(req CtlRequest) (any, error) {
+		time.AfterFunc(100*time.Millisecond, c.cancel) // answer first
+		return nil, nil
+	})
 }
 
 // vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
@@ -0,0 +1,162 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+)
+
+// ctlCmd represents the ctl command
+var ctlCmd = &cobra.Command{
+	Use:   "ctl [-j] [--scope SCOPE] COMMAND [PID [PRESET]]",
+	Short: "Drive running control command",
+	Long: `Send a request to the running control command, through its socket
+
+The COMMAND argument can be one out of 'status', 'reload', 'pause', 'resume',
+'list', 'apply', 'pin', 'unpin' or 'shutdown'.
+The 'apply' command requires the PID argument and applies at once the PRESET,
+or 'auto' when not given, to its process group.
+The 'pin' and 'unpin' commands require the PID argument. See pin and unpin
+commands.
+The 'list' command shows the managed process groups and, when not settled,
+what diverges from their rule.
+The --scope option selects the control command running with SCOPE, one out of
+'user', 'global', 'system' or 'all', 'user' by default.`,
+	ValidArgs:             []string{"status", "reload", "pause", "resume", "list", "apply", "pin", "unpin", "shutdown"},
+	Args:                  cobra.RangeArgs(1, 3),
+	DisableFlagsInUseLine: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		fs := cmd.LocalNonPersistentFlags()
+		// Bind shared flags
+		if err := viper.BindPFlags(fs); err != nil {
+			return err
+		}
+		if err := checkCtlScope(); err != nil {
+			return err
+		}
+		if err := cobra.OnlyValidArgs(cmd, args[:1]); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		max := 1 // command and arguments
+		switch args[0] {
+		case "apply":
+			max = 3
+		case "pin", "unpin":
+			max = 2
+		}
+		switch {
+		case max > 1 && len(args) == 1:
+			return fmt.Errorf("%w: %s requires PID", ErrInvalid, args[0])
+		case len(args) > max && max == 1:
+			return fmt.Errorf("%w: %s accepts no argument", ErrInvalid, args[0])
+		case len(args) > max:
+			return fmt.Errorf("%w: %s accepts at most %d arguments", ErrInvalid, args[0], max-1)
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		// Debug output
+		debugOutput(cmd)
+		// Real job goes here
+		req := CtlRequest{Command: args[0]}
+		if len(args) > 1 {
+			req.Pid = parsePid(args[1])
+		}
+		if len(args) > 2 {
+			req.Preset = args[2]
+		}
+		sendCtlCommand(cmd, req)
+	},
+}
+
+func parsePid(arg string) int {
+	pid, err := strconv.Atoi(arg)
+	if err != nil || pid <= 0 {
+		fatal(fmt.Errorf("%w: bad pid: %s", ErrInvalid, arg))
+	}
+	return pid
+}
+
+// addCtlScopeFlag adds the flag selecting the running control command.
+func addCtlScopeFlag(cmd *cobra.Command) {
+	cmd.Flags().String("scope", "user", "send to control command running with `SCOPE`")
+}
+
+// checkCtlScope checks the scope of the control command to send to.
+func checkCtlScope() error {
+	if scope := viper.GetString("scope"); !(Contains(controlScopes, scope)) {
+		return fmt.Errorf("%w: scope: %s", ErrInvalid, scope)
+	}
+	return nil
+}
+
+// sendCtlCommand sends the request to the running control command and writes
+// the response data, if any.
+func sendCtlCommand(cmd *cobra.Command, req CtlRequest) {
+	resp, err := SendCtlRequest(controlSocket(viper.GetString("scope")), req)
+	fatalIs(err, ErrNotFound)
+	if !(resp.Ok) {
+		fatal(errors.New(resp.Error))
+	}
+	if len(resp.Data) == 0 {
+		return
+	}
+	fatal(wrap(writeCtlData(cmd, resp.Data)))
+}
+
+// writeCtlData writes response data as indented JSON or as YAML.
+func writeCtlData(cmd *cobra.Command, data json.RawMessage) error {
+	if viper.GetBool("json") {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, data, "", "  "); err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), buf.String())
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	content, err := yaml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(cmd.OutOrStdout(), string(content))
+	return nil
+}
+
+func init() {
+	// Persistent flags
+	// Local flags
+	fs := ctlCmd.Flags()
+	fs.SortFlags = false
+	fs.SetInterspersed(false)
+	fs.BoolP("json", "j", false, "use json format")
+	addCtlScopeFlag(ctlCmd)
+	ctlCmd.InheritedFlags().SortFlags = false
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
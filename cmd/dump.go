@@ -25,9 +25,9 @@ import (
 
 // dumpCmd represents the dump command
 var dumpCmd = &cobra.Command{
-	Use:                   "dump [-u|-g|-s|-a] [-r|-j|-n] [-m]",
+	Use:                   "dump [-u|-g|-s|-a] [-r|-j|-n] [-m] [-x] [-t]",
 	Short:                 "Dump processes information",
-	Long:                  `Dump information on the running processes`,
+	Long:                  `Dump information on the running processes, with whether their cgroup is frozen`,
 	Args:                  cobra.MaximumNArgs(0),
 	DisableFlagsInUseLine: true,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
@@ -40,10 +40,11 @@ var dumpCmd = &cobra.Command{
 		// Real job goes here
 		presetCache = GetPresetCache() // get cache, once for all goroutines
 		var (
-			format    = GetStringFromFlags("string", viper.GetStringSlice("formats")...)
-			formatter = GetFormatter(format)
-			scope     = GetStringFromFlags("user", viper.GetStringSlice("scopes")...)
-			filterer  ProcFilterer
+			format          = GetStringFromFlags("string", viper.GetStringSlice("formats")...)
+			formatter       = GetFormatter(format)
+			threadFormatter = GetThreadFormatter(format)
+			scope           = GetStringFromFlags("user", viper.GetStringSlice("scopes")...)
+			filterer        ProcFilterer
 		)
 		if viper.GetBool("manageable") {
 			filterer = presetCache.GetFilterer(scope)
@@ -55,8 +56,29 @@ var dumpCmd = &cobra.Command{
 			fmt.Fprintln(cmd.ErrOrStderr(), "Dumping stats for", filterer.String()+"...")
 		}
 		for _, p := range FilteredProcs(filterer) {
+			if err := p.setFrozen(); err != nil {
+				debug(err)
+			}
+			if viper.GetBool("extra") {
+				if err := p.setDetails(); err != nil {
+					debug(err)
+				}
+			}
+			if viper.GetBool("threads") {
+				threads, err := p.ReadThreads()
+				if err != nil {
+					debug(err)
+				}
+				p.Threads = threads
+			}
 			// cmd.Println(formatter(p))
 			fmt.Fprintln(cmd.OutOrStdout(), formatter(p))
+			if format == "json" { // yet inside process
+				continue
+			}
+			for _, t := range p.Threads {
+				fmt.Fprintln(cmd.OutOrStdout(), "  "+threadFormatter(t))
+			}
 		}
 	},
 }
@@ -70,6 +92,8 @@ func init() {
 	viper.Set("scopes", addScopeFlags(dumpCmd))
 	viper.Set("formats", addFormatFlags(dumpCmd))
 	fs.BoolP("manageable", "m", false, "only manageable processes")
+	fs.BoolP("extra", "x", false, "also read cmdline, exe, status, io and schedstat")
+	fs.BoolP("threads", "t", false, "also show the threads of the processes")
 	// addVerboseFlag(dumpCmd)
 	dumpCmd.InheritedFlags().SortFlags = false
 }
@@ -78,6 +78,16 @@ func fatal(e error) {
 	}
 }
 
+// fatalIs is like fatal, but exits with the code of target when the error
+// wraps it.
+func fatalIs(e error, target customError) {
+	if errors.Is(e, target) {
+		warn(e)
+		os.Exit(target.Code)
+	}
+	fatal(e)
+}
+
 func nonfatal(e error) bool {
 	if e != nil {
 		inform("warning", e.Error())
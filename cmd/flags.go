@@ -41,6 +41,15 @@ func addDryRunFlag(cmd *cobra.Command) {
 	fs.BoolP("dry-run", "n", false, "display external commands instead running them")
 }
 
+func addMatchFlags(cmd *cobra.Command) (names []string) {
+	fs := cmd.Flags()
+	fs.Bool("inherit", false, "apply rules to descendant processes")
+	fs.Int("depth", 0, "inherit rules down to `N` generations (0 for unlimited)")
+	fs.Bool("group", false, "apply rule to process group when any member matches")
+	names = append(names, "inherit", "depth", "group")
+	return
+}
+
 // func addCacheFlag(cmd *cobra.Command) {
 // fs := cmd.Flags()
 // fs.BoolP("force", "f", false, "ignore existing cache and forcefully build new")
@@ -0,0 +1,109 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"github.com/spf13/viper"
+)
+
+// Foreground returns whether the process belongs to the foreground process
+// group of its terminal, and is neither stopped nor traced.
+func (p *Proc) Foreground() bool {
+	return p.TtyNr != 0 && p.Pgrp == p.TPGid && p.State != "T" && p.State != "t"
+}
+
+// foregroundEnabled returns whether control command boosts the foreground
+// process groups.
+func foregroundEnabled() bool {
+	return procState != nil && viper.GetString("foreground") != ""
+}
+
+// foregroundProcs returns, among procs without any rule, those to review
+// anyway, because their process group must be boosted or restored. The
+// process groups with some ruled process are left to their rule.
+func foregroundProcs(procs []*Proc, ruled []*Proc) (result []*Proc) {
+	if !(foregroundEnabled()) {
+		return
+	}
+	groups := make(map[int]bool)
+	for _, p := range ruled {
+		groups[p.Pgrp] = true
+	}
+	for _, p := range procs {
+		if !(groups[p.Pgrp]) && (p.Foreground() || procState.Boosted(p)) {
+			result = append(result, p)
+		}
+	}
+	return
+}
+
+// foregroundOnly returns whether the process group of leader is reviewed only
+// to be boosted or restored, without any rule of its own.
+func (pc *PresetCache) foregroundOnly(leader *ProcJob) bool {
+	if !(foregroundEnabled()) || leader.Request.Preset != "auto" {
+		return false
+	}
+	_, err := pc.Rule(leader.Proc.RuleName())
+	return err != nil
+}
+
+// ForegroundProfile returns the nice and I/O attributes of the profile set
+// with foreground option, if any. Only control command boosts the
+// foreground process groups.
+func (pc *PresetCache) ForegroundProfile() (BaseProfile, bool) {
+	if !(foregroundEnabled()) {
+		return BaseProfile{}, false
+	}
+	profile, err := pc.Profile(viper.GetString("foreground"))
+	if err != nil {
+		return BaseProfile{}, false
+	}
+	return BaseProfile{
+		Nice:    profile.Nice,
+		IOClass: profile.IOClass,
+		IONice:  profile.IONice,
+	}, true
+}
+
+// Boost applies the foreground profile over the rule of the leader of a
+// foreground process group. When the group is not in the foreground anymore,
+// the rule applies again, with the default nice and I/O attributes unless
+// the rule sets them. It returns the number of attributes to restore that the
+// rule diff does not count.
+func (pc *PresetCache) Boost(leader *ProcJob, runtime BaseProfile) (count int) {
+	boost, ok := pc.ForegroundProfile()
+	if !(ok) {
+		return
+	}
+	if leader.Proc.Foreground() {
+		procState.Boost(leader.Proc)
+		OverrideBase(boost, &leader.Rule.BaseProfile)
+		return
+	}
+	if !(procState.Boosted(leader.Proc)) {
+		return
+	}
+	restore := BaseProfile{IOClass: "none"}
+	OverrideBase(leader.Rule.BaseProfile, &restore)
+	leader.Rule.BaseProfile = restore
+	if restore.Nice == 0 && runtime.Nice != 0 { // nice is always adjusted
+		count++
+	}
+	return
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
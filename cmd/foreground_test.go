@@ -0,0 +1,135 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// ttyProc returns the leader of some process group of the terminal, whose
+// foreground process group is tpgid.
+func ttyProc(pid, tpgid int, state string) *Proc {
+	p := newProc(pid, pid, "vim", uint64(pid))
+	p.TtyNr, p.TPGid, p.State = 34817, tpgid, state
+	return p
+}
+
+func TestForeground(t *testing.T) {
+	tests := []struct {
+		name string
+		p    *Proc
+		want bool
+	}{
+		{"foreground", ttyProc(42, 42, "S"), true},
+		{"running", ttyProc(42, 42, "R"), true},
+		{"background", ttyProc(42, 40, "S"), false},
+		{"stopped", ttyProc(42, 42, "T"), false},
+		{"no terminal", newProc(42, 42, "vim", 42), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.p.Foreground(); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// foregroundCache returns some cache with the Foreground profile.
+func foregroundCache(t *testing.T) *PresetCache {
+	t.Helper()
+	defer viper.Set("confdirs", viper.GetStringSlice("confdirs"))
+	viper.Set("confdirs", []string{writeConfig(t, `presets:
+  appgroups:
+    none: {}
+    Foreground:
+      profile:
+        nice: -5
+        ioclass: best-effort
+        ionice: 2
+`)})
+	pc, err := ReloadPresetCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pc
+}
+
+func TestPresetCacheBoost(t *testing.T) {
+	pc := foregroundCache(t)
+	tests := []struct {
+		name       string
+		foreground string
+		boosted    bool // previously
+		p          *Proc
+		rule       BaseProfile
+		runtime    BaseProfile
+		want       BaseProfile
+		wantCount  int
+	}{
+		{"boost", "Foreground", false, ttyProc(42, 42, "S"), BaseProfile{Nice: 5, OomScoreAdj: 100}, BaseProfile{}, BaseProfile{Nice: -5, IOClass: "best-effort", IONice: 2, OomScoreAdj: 100}, 0},
+		{"boost again", "Foreground", true, ttyProc(42, 42, "S"), BaseProfile{Nice: 5}, BaseProfile{Nice: -5}, BaseProfile{Nice: -5, IOClass: "best-effort", IONice: 2}, 0},
+		{"background", "Foreground", false, ttyProc(42, 40, "S"), BaseProfile{Nice: 5}, BaseProfile{}, BaseProfile{Nice: 5}, 0},
+		{"restore rule", "Foreground", true, ttyProc(42, 40, "S"), BaseProfile{Nice: 5, IOClass: "idle"}, BaseProfile{Nice: -5}, BaseProfile{Nice: 5, IOClass: "idle"}, 0},
+		{"restore default", "Foreground", true, ttyProc(42, 40, "S"), BaseProfile{OomScoreAdj: 100}, BaseProfile{Nice: -5}, BaseProfile{IOClass: "none", OomScoreAdj: 100}, 1},
+		{"restore stopped", "Foreground", true, ttyProc(42, 42, "T"), BaseProfile{}, BaseProfile{Nice: -5}, BaseProfile{IOClass: "none"}, 1},
+		{"restore default nice", "Foreground", true, ttyProc(42, 40, "S"), BaseProfile{}, BaseProfile{}, BaseProfile{IOClass: "none"}, 0},
+		{"disabled", "", false, ttyProc(42, 42, "S"), BaseProfile{Nice: 5}, BaseProfile{}, BaseProfile{Nice: 5}, 0},
+		{"unknown profile", "Interactive", false, ttyProc(42, 42, "S"), BaseProfile{Nice: 5}, BaseProfile{}, BaseProfile{Nice: 5}, 0},
+	}
+	defer func(s *ProcState, foreground string) {
+		procState = s
+		viper.Set("foreground", foreground)
+	}(procState, viper.GetString("foreground"))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("foreground", tt.foreground)
+			procState = NewProcState(0)
+			if tt.boosted {
+				procState.Boost(tt.p)
+			}
+			leader := &ProcJob{Proc: tt.p, Rule: Rule{BaseProfile: tt.rule}}
+			count := pc.Boost(leader, tt.runtime)
+			if got := leader.Rule.BaseProfile; !reflect.DeepEqual(got, tt.want) || count != tt.wantCount {
+				t.Errorf("got (%+v, %d), want (%+v, %d)", got, count, tt.want, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestProcStateSelectForeground(t *testing.T) {
+	defer viper.Set("foreground", viper.GetString("foreground"))
+	viper.Set("foreground", "Foreground")
+	background := ttyProc(42, 40, "S")
+	s := settledState(background)
+	if ready, _ := s.Select([]*Proc{background}); len(ready) > 0 {
+		t.Fatalf("got %v ready while settled", pids(ready))
+	}
+	foreground := ttyProc(42, 42, "S")
+	if ready, _ := s.Select([]*Proc{foreground}); !reflect.DeepEqual(pids(ready), []int{42}) {
+		t.Errorf("got %v ready once in the foreground, want [42]", pids(ready))
+	}
+	s.Settle(leaderJob(foreground, BaseProfile{}))
+	if ready, _ := s.Select([]*Proc{background}); !reflect.DeepEqual(pids(ready), []int{42}) {
+		t.Errorf("got %v ready once in the background, want [42]", pids(ready))
+	}
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
@@ -0,0 +1,267 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// freezeLong describes the targets of freeze and thaw commands.
+const freezeLong = `
+The CGROUP argument is some cgroup preset, like cpu33, or its nicy slice, like
+nicy-cpu33.slice, inside the service manager of the calling user. The RULE
+argument is the name of some rule, targeting the units of the processes that
+it matches, like the control command does, inherited or shared in the process
+group included. The PID argument targets the unit of the process, usually some
+scope. Session scopes and the unit running nicy are never targeted.
+The units are frozen or thawed through their service manager, when available,
+or writing their cgroup.freeze file otherwise.
+Only superuser can target the processes of other users.`
+
+// freezeCmd represents the freeze command
+var freezeCmd = &cobra.Command{
+	Use:   "freeze [-n] (CGROUP|RULE|PID)...",
+	Short: "Freeze nicy slices and scopes",
+	Long: `Freeze all the processes inside some nicy slices or scopes
+` + freezeLong,
+	Args:                  cobra.MinimumNArgs(1),
+	DisableFlagsInUseLine: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		// Bind shared flags
+		if err := viper.BindPFlags(cmd.LocalNonPersistentFlags()); err != nil {
+			return err
+		}
+		return checkLiveProcFS()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		viper.Set("tag", "freeze")
+		// Debug output
+		debugOutput(cmd)
+		// Real job goes here
+		presetCache = GetPresetCache() // get cache content, once for all goroutines
+		std := &Streams{Stdin: nil, Stdout: cmd.OutOrStdout(), Stderr: cmd.ErrOrStderr()}
+		fatal(wrap(doFreezeCmd("", args, true, std)))
+	},
+}
+
+// thawCmd represents the thaw command
+var thawCmd = &cobra.Command{
+	Use:   "thaw [-n] (CGROUP|RULE|PID)...",
+	Short: "Thaw nicy slices and scopes",
+	Long: `Thaw all the processes inside some nicy slices or scopes
+` + freezeLong,
+	Args:                  cobra.MinimumNArgs(1),
+	DisableFlagsInUseLine: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		// Bind shared flags
+		if err := viper.BindPFlags(cmd.LocalNonPersistentFlags()); err != nil {
+			return err
+		}
+		return checkLiveProcFS()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		viper.Set("tag", "thaw")
+		// Debug output
+		debugOutput(cmd)
+		// Real job goes here
+		presetCache = GetPresetCache() // get cache content, once for all goroutines
+		std := &Streams{Stdin: nil, Stdout: cmd.OutOrStdout(), Stderr: cmd.ErrOrStderr()}
+		fatal(wrap(doFreezeCmd("", args, false, std)))
+	},
+}
+
+func init() {
+	// Persistent flags
+	// Local flags
+	for _, cmd := range []*cobra.Command{freezeCmd, thawCmd} {
+		fs := cmd.Flags()
+		fs.SortFlags = false
+		fs.SetInterspersed(false)
+		addDryRunFlag(cmd)
+		cmd.InheritedFlags().SortFlags = false
+	}
+}
+
+// freezeTarget is some unit to freeze or thaw, with its cgroup at Path,
+// relative to the cgroup filesystem.
+type freezeTarget struct {
+	Unit    string
+	Path    string
+	manager string
+	prefix  []string
+}
+
+// sliceTarget returns the nicy slice for cgroup key, inside the service
+// manager of the calling user.
+func sliceTarget(key string) freezeTarget {
+	manager := "--system"
+	if os.Getuid() != 0 {
+		manager = "--user"
+	}
+	return freezeTarget{
+		Unit:    "nicy-" + key + ".slice",
+		Path:    nicySliceCgroup(key),
+		manager: manager,
+	}
+}
+
+// procTarget returns the unit of the process.
+func procTarget(p *Proc) (t freezeTarget, err error) {
+	job := NewProcJob(p)
+	path := strings.TrimPrefix(p.Cgroup, "0::/")
+	switch {
+	case path == "" || p.Unit == "":
+		return t, fmt.Errorf("%w: %s[%d]: no unit", ErrInvalid, p.Comm, p.Pid)
+	case job.inSessionScope():
+		return t, fmt.Errorf("%w: %s[%d]: session scope %s", ErrInvalid, p.Comm, p.Pid, p.Unit)
+	}
+	t = freezeTarget{Unit: p.Unit, Path: path, manager: "--system"}
+	if job.inManagedSlice() {
+		t.manager, t.prefix = "--user", job.prefix()
+	} else if os.Getuid() != 0 {
+		t.prefix = []string{"$SUDO"}
+	}
+	return
+}
+
+// command returns the command that freezes or thaws the unit.
+func (t freezeTarget) command(frozen bool) Command {
+	method := "ThawUnit"
+	if frozen {
+		method = "FreezeUnit"
+	}
+	tokens := append(Clone(t.prefix),
+		"busctl", "call", "--quiet", t.manager,
+		"org.freedesktop.systemd1", "/org/freedesktop/systemd1",
+		"org.freedesktop.systemd1.Manager", method, "s", t.Unit,
+	)
+	return NewCommand(tokens...)
+}
+
+// Apply freezes or thaws the unit through its service manager, or writing its
+// cgroup.freeze file when it fails.
+func (t freezeTarget) Apply(frozen bool, tag string, std *Streams) error {
+	c := t.command(frozen)
+	err := c.StartWait(tag, std)
+	if err == nil {
+		return nil
+	}
+	debug(fmt.Errorf("%w: %s: %v", ErrFailure, t.Unit, err))
+	if viper.GetBool("dry-run") || viper.GetBool("verbose") {
+		inform(tag, fmt.Sprintf("%s: cgroup.freeze: %v", t.Unit, frozen))
+	}
+	if viper.GetBool("dry-run") {
+		return nil
+	}
+	if err := FreezeCgroup(t.Path, frozen); err != nil {
+		return fmt.Errorf("%w: %s", err, t.Unit)
+	}
+	return nil
+}
+
+// freezeTargets returns the units targeted by arg, trying some cgroup preset
+// or its nicy slice first, then some process id, then some rule name.
+func freezeTargets(pc *PresetCache, arg string) (result []freezeTarget, err error) {
+	key := strings.TrimSuffix(strings.TrimPrefix(arg, "nicy-"), ".slice")
+	if (arg == key || arg == "nicy-"+key+".slice") && pc.HasPreset("cgroup", key) {
+		return []freezeTarget{sliceTarget(key)}, nil
+	}
+	if pid, err := strconv.Atoi(arg); err == nil {
+		found := SelectedProcs([]int{pid}, GetFilterer("all"))
+		if len(found) == 0 {
+			return nil, fmt.Errorf("%w: process %d", ErrNotFound, pid)
+		}
+		t, err := procTarget(found[0])
+		if err != nil {
+			return nil, err
+		}
+		return []freezeTarget{t}, nil
+	}
+	if !(pc.HasPreset("rule", arg)) {
+		return nil, fmt.Errorf("%w: cgroup, rule or process %s", ErrNotFound, arg)
+	}
+	uid := os.Getuid()
+	procs := FilteredProcs(ProcFilter{
+		FilterProc: FilterProc{
+			Filter: func(p *Proc, err error) bool {
+				return err == nil && (uid == 0 || p.Uid == uid)
+			},
+			Message: fmt.Sprintf("processes matching rule %s", arg),
+		},
+		Lazy: true,
+	})
+	pc.Resolve(procs) // inherited or shared in the process group, like control
+	units := make(map[string]bool)
+	for _, p := range procs {
+		if p.RuleName() != arg {
+			continue
+		}
+		t, err := procTarget(p)
+		if err != nil {
+			warn(err)
+			continue
+		}
+		if !(units[t.Path]) {
+			units[t.Path] = true
+			result = append(result, t)
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("%w: cgroup, rule or process %s", ErrNotFound, arg)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	return
+}
+
+// doFreezeCmd freezes or thaws the units targeted by args, except the one
+// running nicy.
+func doFreezeCmd(tag string, args []string, frozen bool, std *Streams) (err error) {
+	var targets []freezeTarget
+	for _, arg := range args {
+		found, err := freezeTargets(&presetCache, arg)
+		if err != nil {
+			return err
+		}
+		targets = append(targets, found...)
+	}
+	own := strings.TrimPrefix(GetCalling().Cgroup, "0::/")
+	for _, t := range targets {
+		if own == t.Path || strings.HasPrefix(own, t.Path+"/") {
+			warn(fmt.Errorf("%w: %s: running nicy", ErrInvalid, t.Unit))
+			continue
+		}
+		if failure := t.Apply(frozen, tag, std); failure != nil {
+			if err == nil {
+				err = failure
+			} else {
+				nonfatal(failure)
+			}
+		}
+	}
+	return
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
@@ -0,0 +1,64 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestFreezeTargets(t *testing.T) {
+	useTestdata(t)
+	pc := testPresetCache(t)
+	tests := []struct {
+		name    string
+		arg     string
+		want    []string // units
+		wantErr error
+	}{
+		{"cgroup", "cpu33", []string{"nicy-cpu33.slice"}, nil},
+		{"slice", "nicy-cpu33.slice", []string{"nicy-cpu33.slice"}, nil},
+		{"rule", "pulseaudio", []string{"pulseaudio.service"}, nil},
+		{"rule of group", "firefox", []string{"app-firefox.scope"}, nil},
+		{"pid", "4243", []string{"app-firefox.scope"}, nil},
+		{"unknown pid", "4245", nil, ErrNotFound},
+		{"process without rule", "vim", nil, ErrNotFound},
+		{"unknown", "nicy-cpu50.slice", nil, ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if uid := os.Getuid(); tt.wantErr == nil && uid != 0 && uid != 1000 {
+				t.Skip("processes of some other user")
+			}
+			got, err := freezeTargets(pc, tt.arg)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			var units []string
+			for _, target := range got {
+				units = append(units, target.Unit)
+			}
+			if !(reflect.DeepEqual(units, tt.want)) {
+				t.Errorf("got %v, want %v", units, tt.want)
+			}
+		})
+	}
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
@@ -16,6 +16,8 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 */
 package cmd
 
+import "sort"
+
 func Clone[S ~[]E, E any](s S) S {
 	result := make(S, len(s))
 	_ = copy(result, s)
@@ -59,6 +61,25 @@ func Reduce[S ~[]E, E, T any](s S, init T, f func(T, E) T) T {
 	return r
 }
 
+func Contains[S ~[]E, E comparable](s S, v E) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Keys returns the sorted keys of m.
+func Keys[M ~map[string]V, V any](m M) []string {
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
+}
+
 func ChanFirst[C chan T, T any](ch C, f func(T) bool) T {
 	for v := range ch {
 		if f(v) {
@@ -50,7 +50,7 @@ type ProcJob struct {
 }
 
 func NewProcJob(p *Proc) *ProcJob {
-	name := strings.Split(p.Comm, `:`)[0]
+	name := p.RuleName()
 	req := NewRequest(name, fmt.Sprintf("%%%s%%", name), "/bin/sh")
 	req.Quiet = true
 	return &ProcJob{Proc: p, Request: req}
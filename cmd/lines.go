@@ -79,6 +79,29 @@ func (c *Command) RequireSysCapability() bool {
 	return false
 }
 
+// Utility returns the name of the utility that the command runs, skipping
+// the exec, sudo, runuser and env prefixes and the environment variables.
+func (c *Command) Utility() string {
+	tokens := c.Content()
+	for i := 0; i < len(tokens); i++ {
+		switch token := tokens[i]; token {
+		case "exec", "$SUDO", viper.GetString("sudo"), "env":
+			continue
+		case "runuser": // and options, up to --
+			for i < len(tokens) && tokens[i] != "--" {
+				i++
+			}
+			continue
+		default:
+			if name, _, found := strings.Cut(token, "="); found && !(strings.Contains(name, "/")) {
+				continue // environment variable
+			}
+			return filepath.Base(token)
+		}
+	}
+	return ""
+}
+
 func (c *Command) Runtime(pid, uid int) Command {
 	var tokens []string
 	for _, token := range c.Content() {
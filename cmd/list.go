@@ -35,7 +35,9 @@ var listCmd = &cobra.Command{
 The CATEGORY argument can be one out of 'rules', 'profiles' or 'cgroups'.
 The ORIGIN argument can be one out of 'vendor', 'site', 'user', or
 'other' when outside standard directories.
-When filtering from ORIGIN, show otherwise removed duplicates.`,
+When filtering from ORIGIN, show otherwise removed duplicates.
+The state of the nicy slice of each cgroup, inside the service manager of the
+calling user, is shown: frozen, running, or - when not started.`,
 	ValidArgs:             []string{"cgroups", "profiles", "rules"},
 	Args:                  cobra.ExactValidArgs(1),
 	DisableFlagsInUseLine: true,
@@ -78,8 +80,13 @@ When filtering from ORIGIN, show otherwise removed duplicates.`,
 		}
 		sort.Strings(list)
 		fatal(wrap(err))
+		header := fmt.Sprintf("%s\torigin\tcontent", category)
+		if category == "cgroup" {
+			header = "cgroup\torigin\tstate\tcontent"
+			list = Map(list, withSliceState)
+		}
 		if !viper.GetBool("no-headers") {
-			fmt.Fprintln(tw, fmt.Sprintf("%s\torigin\tcontent", category))
+			fmt.Fprintln(tw, header)
 		}
 		for _, line := range list {
 			fmt.Fprintln(tw, line)
@@ -87,6 +94,23 @@ When filtering from ORIGIN, show otherwise removed duplicates.`,
 	},
 }
 
+// withSliceState inserts the state of the nicy slice into the line listing
+// some cgroup.
+func withSliceState(line string) string {
+	fields := strings.SplitN(line, "\t", 3)
+	if len(fields) < 3 {
+		return line
+	}
+	state := "-"
+	if frozen, err := CgroupFrozen(nicySliceCgroup(fields[0])); err == nil {
+		state = "running"
+		if frozen {
+			state = "frozen"
+		}
+	}
+	return strings.Join([]string{fields[0], fields[1], state, fields[2]}, "\t")
+}
+
 func init() {
 	// Persistent flags
 	// Local flags
@@ -0,0 +1,192 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// controlMetrics collects what control command does. It is nil for any other
+// command, or when metrics are not required.
+var controlMetrics *Metrics
+
+// Metrics collects counters, exposed in the Prometheus text format.
+type Metrics struct {
+	mu          sync.Mutex
+	scans       uint64
+	scanSeconds float64
+	matched     map[string]uint64 // per rule
+	commands    map[string]uint64 // per utility
+	failures    map[string]uint64 // per utility
+	Diverging   func() int        // gauge, computed when exposed
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		matched:  make(map[string]uint64),
+		commands: make(map[string]uint64),
+		failures: make(map[string]uint64),
+	}
+}
+
+// Scan counts a full scan of the running processes.
+func (m *Metrics) Scan(d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.scans++
+	m.scanSeconds += d.Seconds()
+}
+
+// Matched counts a new process matching the rule.
+func (m *Metrics) Matched(rule string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.matched[rule]++
+}
+
+// Command counts a command executed with utility and whether it failed.
+func (m *Metrics) Command(utility string, err error) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.commands[utility]++
+	if err != nil {
+		m.failures[utility]++
+	}
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func writeMetric(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func writeLabeled(w io.Writer, name, label string, values map[string]uint64) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s{%s=\"%s\"} %d\n", name, label, escapeLabel(key), values[key])
+	}
+}
+
+// Expose writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) Expose(w io.Writer) error {
+	if m == nil {
+		return nil
+	}
+	var diverging int
+	if m.Diverging != nil {
+		diverging = m.Diverging()
+	}
+	bw := bufio.NewWriter(w)
+	m.mu.Lock()
+	writeMetric(bw, "nicy_scans_total", "counter", "Full scans of the running processes.")
+	fmt.Fprintf(bw, "nicy_scans_total %d\n", m.scans)
+	writeMetric(bw, "nicy_scan_duration_seconds", "summary", "Duration of the full scans.")
+	fmt.Fprintf(bw, "nicy_scan_duration_seconds_sum %g\n", m.scanSeconds)
+	fmt.Fprintf(bw, "nicy_scan_duration_seconds_count %d\n", m.scans)
+	writeMetric(bw, "nicy_processes_matched_total", "counter", "Processes found matching a rule, per rule.")
+	writeLabeled(bw, "nicy_processes_matched_total", "rule", m.matched)
+	writeMetric(bw, "nicy_commands_total", "counter", "Commands executed, per utility.")
+	writeLabeled(bw, "nicy_commands_total", "utility", m.commands)
+	writeMetric(bw, "nicy_command_failures_total", "counter", "Commands failed, per utility.")
+	writeLabeled(bw, "nicy_command_failures_total", "utility", m.failures)
+	m.mu.Unlock()
+	writeMetric(bw, "nicy_groups_diverging", "gauge", "Process groups diverging from their rule.")
+	fmt.Fprintf(bw, "nicy_groups_diverging %d\n", diverging)
+	return bw.Flush()
+}
+
+// WriteFile writes the metrics into the file at path, for some textfile
+// collector. The file is replaced atomically.
+func (m *Metrics) WriteFile(path string) error {
+	var buf bytes.Buffer
+	if err := m.Expose(&buf); err != nil {
+		return err
+	}
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("%w: metrics: %v", ErrFailure, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("%w: metrics: %v", ErrFailure, err)
+	}
+	return nil
+}
+
+// Serve serves the metrics over HTTP, until the context is done. The
+// address is either some unix socket path, with optional "unix:" prefix, or
+// some TCP host:port.
+func (m *Metrics) Serve(ctx context.Context, address string) error {
+	network := "tcp"
+	if strings.HasPrefix(address, "unix:") || strings.HasPrefix(address, "/") {
+		network = "unix"
+		address = strings.TrimPrefix(address, "unix:")
+		if err := removeStaleSocket("metrics", address); err != nil {
+			return err
+		}
+	}
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return fmt.Errorf("%w: metrics: %v", ErrFailure, err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		m.Expose(w)
+	})
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	go func() {
+		if err := server.Serve(listener); !(errors.Is(err, http.ErrServerClosed)) {
+			nonfatal(fmt.Errorf("%w: metrics: %v", ErrFailure, err))
+		}
+	}()
+	return nil
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
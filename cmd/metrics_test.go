@@ -0,0 +1,147 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsExpose(t *testing.T) {
+	tests := []struct {
+		name    string
+		metrics func() *Metrics
+		want    []string // lines, in order
+	}{
+		{"nil", func() *Metrics { return nil }, nil},
+		{"empty", NewMetrics, []string{
+			"nicy_scans_total 0",
+			"nicy_scan_duration_seconds_sum 0",
+			"nicy_scan_duration_seconds_count 0",
+			"# TYPE nicy_processes_matched_total counter",
+			"# HELP nicy_commands_total Commands executed, per utility.",
+			"# TYPE nicy_groups_diverging gauge",
+			"nicy_groups_diverging 0",
+		}},
+		{"counted", func() *Metrics {
+			m := NewMetrics()
+			m.Scan(1500 * time.Millisecond)
+			m.Scan(500 * time.Millisecond)
+			m.Matched("firefox")
+			m.Matched("pulseaudio")
+			m.Matched("firefox")
+			m.Command("renice", nil)
+			m.Command("renice", nil)
+			m.Command("chrt", errors.New("denied"))
+			m.Diverging = func() int { return 3 }
+			return m
+		}, []string{
+			"nicy_scans_total 2",
+			"nicy_scan_duration_seconds_sum 2",
+			"nicy_scan_duration_seconds_count 2",
+			`nicy_processes_matched_total{rule="firefox"} 2`,
+			`nicy_processes_matched_total{rule="pulseaudio"} 1`,
+			`nicy_commands_total{utility="chrt"} 1`,
+			`nicy_commands_total{utility="renice"} 2`,
+			`nicy_command_failures_total{utility="chrt"} 1`,
+			"nicy_groups_diverging 3",
+		}},
+		{"escaped", func() *Metrics {
+			m := NewMetrics()
+			m.Matched("a\"b\\c\nd")
+			return m
+		}, []string{
+			`nicy_processes_matched_total{rule="a\"b\\c\nd"} 1`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.metrics().Expose(&buf); err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == nil {
+				if buf.Len() > 0 {
+					t.Errorf("got %q, want nothing", buf.String())
+				}
+				return
+			}
+			i := 0 // wanted lines appear in order
+			for _, line := range strings.Split(buf.String(), "\n") {
+				if i < len(tt.want) && line == tt.want[i] {
+					i++
+				}
+			}
+			if i < len(tt.want) {
+				t.Errorf("missing %q in:\n%s", tt.want[i], buf.String())
+			}
+		})
+	}
+}
+
+func TestMetricsExposeFailures(t *testing.T) {
+	m := NewMetrics()
+	m.Command("renice", nil)
+	var buf bytes.Buffer
+	if err := m.Expose(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "nicy_command_failures_total{") {
+		t.Errorf("got failures without any:\n%s", buf.String())
+	}
+}
+
+func TestMetricsServeSocket(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dir := t.TempDir()
+	// anything else than a socket is left
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewMetrics().Serve(ctx, file); !errors.Is(err, ErrInvalid) {
+		t.Errorf("got error %v with regular file, want %v", err, ErrInvalid)
+	}
+	if data, err := os.ReadFile(file); err != nil || string(data) != "data" {
+		t.Errorf("regular file changed: %q, %v", data, err)
+	}
+	// stale socket
+	stale := filepath.Join(dir, "metrics.sock")
+	listener, err := net.Listen("unix", stale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	listener.Close()
+	if err := NewMetrics().Serve(ctx, "unix:"+stale); err != nil {
+		t.Fatalf("got error %v with stale socket", err)
+	}
+	// socket in use
+	if err := NewMetrics().Serve(ctx, stale); !errors.Is(err, ErrAlready) {
+		t.Errorf("got error %v with running server, want %v", err, ErrAlready)
+	}
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
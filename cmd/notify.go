@@ -0,0 +1,79 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Service manager notification
+// See sd_notify(3)
+
+// sdNotify sends the state lines to the service manager, when running as
+// a notify service. Otherwise, it does nothing.
+func sdNotify(state ...string) error {
+	name := os.Getenv("NOTIFY_SOCKET")
+	if name == "" {
+		return nil
+	}
+	if strings.HasPrefix(name, "@") { // abstract namespace
+		name = "\x00" + name[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: name, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("%w: notify socket: %v", ErrFailure, err)
+	}
+	defer conn.Close()
+	if _, err = conn.Write([]byte(strings.Join(state, "\n"))); err != nil {
+		return fmt.Errorf("%w: notify socket: %v", ErrFailure, err)
+	}
+	return nil
+}
+
+// sdReloading returns the state lines announcing a reload.
+func sdReloading() []string {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts); err != nil {
+		return []string{"RELOADING=1"}
+	}
+	usec := ts.Nano() / int64(time.Microsecond)
+	return []string{"RELOADING=1", "MONOTONIC_USEC=" + strconv.FormatInt(usec, 10)}
+}
+
+// sdWatchdog returns the interval between keep-alive pings expected by the
+// service manager, or zero when the watchdog is disabled.
+func sdWatchdog() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if s := os.Getenv("WATCHDOG_PID"); s != "" {
+		if pid, err := strconv.Atoi(s); err != nil || pid != os.Getpid() {
+			return 0
+		}
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
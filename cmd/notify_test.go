@@ -0,0 +1,97 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSdNotify(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	if err := sdNotify("READY=1"); err != nil {
+		t.Errorf("got error %v without socket", err)
+	}
+	path := filepath.Join(t.TempDir(), "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	t.Setenv("NOTIFY_SOCKET", path)
+	if err := sdNotify("READY=1", "STATUS=Managing 3 processes"); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 256)
+	conn.SetDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(buf[:n]), "READY=1\nSTATUS=Managing 3 processes"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	t.Setenv("NOTIFY_SOCKET", filepath.Join(t.TempDir(), "missing"))
+	if err := sdNotify("READY=1"); err == nil {
+		t.Error("got no error with missing socket")
+	}
+}
+
+func TestSdReloading(t *testing.T) {
+	state := sdReloading()
+	if len(state) != 2 || state[0] != "RELOADING=1" || !(strings.HasPrefix(state[1], "MONOTONIC_USEC=")) {
+		t.Fatalf("got %q", state)
+	}
+	if usec, err := strconv.ParseInt(strings.TrimPrefix(state[1], "MONOTONIC_USEC="), 10, 64); err != nil || usec <= 0 {
+		t.Errorf("got %q, want some positive number of microseconds", state[1])
+	}
+}
+
+func TestSdWatchdog(t *testing.T) {
+	self := strconv.Itoa(os.Getpid())
+	tests := []struct {
+		name string
+		usec string
+		pid  string
+		want time.Duration
+	}{
+		{"disabled", "", "", 0},
+		{"enabled", "30000000", "", 30 * time.Second},
+		{"own pid", "30000000", self, 30 * time.Second},
+		{"other pid", "30000000", "1", 0},
+		{"bad pid", "30000000", "nicy", 0},
+		{"zero", "0", self, 0},
+		{"negative", "-5", self, 0},
+		{"bad interval", "30s", self, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("WATCHDOG_USEC", tt.usec)
+			t.Setenv("WATCHDOG_PID", tt.pid)
+			if got := sdWatchdog(); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
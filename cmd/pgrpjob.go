@@ -32,6 +32,7 @@ type ProcGroupJob struct {
 	Commands []Command  `json:"commands"`
 	Jobs     []*ProcJob `json:"jobs"`
 	leader   *ProcJob   `json:"-"`
+	threads  []threadOverride
 }
 
 func (job *ProcGroupJob) adjustProperties() error {
@@ -53,6 +54,10 @@ func (job *ProcGroupJob) adjustProperties() error {
 			procjob.AdjustOomScoreAdj(procjob.Proc.Pid)
 		}
 	}
+	// using leader ProcJob and each thread with some profile of its own
+	for _, o := range job.threads {
+		j.AdjustThread(o)
+	}
 	return nil
 }
 
@@ -188,7 +193,8 @@ func (job *ProcGroupJob) PrepareAdjust() error {
 	return nil
 }
 
-func (job *ProcGroupJob) Run(tag string, std *Streams) error {
+// Run runs the commands and returns the first failure, if any.
+func (job *ProcGroupJob) Run(tag string, std *Streams) (failure error) {
 	if len(job.Jobs) == 0 {
 		return nil
 	}
@@ -215,19 +221,55 @@ func (job *ProcGroupJob) Run(tag string, std *Streams) error {
 			}
 			continue
 		}
-		if err := c.StartWait(id, std); err != nil {
-			warn(err)
+		err := c.StartWait(id, std)
+		if !(viper.GetBool("dry-run")) {
+			controlMetrics.Command(c.Utility(), err)
+		}
+		if err != nil {
+			failure = fmt.Errorf("%w: %s: %s: %v", ErrFailure, id, c.Utility(), err)
 			break // exit loop on error
 		}
 	}
 	nonfatal(updatePrivileges(false)) // reset ambient capabilities
 	for _, c := range unprivileged {  // don't require any capability
-		if err := c.StartWait(id, std); err != nil {
-			warn(err)
+		err := c.StartWait(id, std)
+		if !(viper.GetBool("dry-run")) {
+			controlMetrics.Command(c.Utility(), err)
+		}
+		if err != nil {
+			if failure == nil {
+				failure = fmt.Errorf("%w: %s: %s: %v", ErrFailure, id, c.Utility(), err)
+			}
 			break // exit loop on error
 		}
 	}
-	return nil
+	return
+}
+
+// NewProcGroupJob returns the job for procs, that share the same process
+// group, or nil when procs is empty.
+func NewProcGroupJob(procs []*Proc) *ProcGroupJob {
+	jobs := ProcToProcJob(procs)
+	if len(jobs) == 0 {
+		return nil
+	}
+	// sort content
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].Proc.Pid < jobs[j].Proc.Pid
+	})
+	leader := jobs[0]
+	groupjob := &ProcGroupJob{ // new group job
+		Pgrp:   leader.Proc.Pgrp,
+		Pids:   []int{leader.Proc.Pid},
+		Diff:   Rule{},
+		Jobs:   []*ProcJob{leader},
+		leader: leader,
+	}
+	for _, job := range jobs[1:] { // add content
+		groupjob.Pids = append(groupjob.Pids, job.Proc.Pid)
+		groupjob.Jobs = append(groupjob.Jobs, job)
+	}
+	return groupjob
 }
 
 func ProcToProcJob(procs []*Proc) []*ProcJob {
@@ -0,0 +1,129 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/spf13/viper"
+	"golang.org/x/sys/unix"
+)
+
+// PidFile is a file holding the pid of the running instance, locked as long
+// as the instance runs.
+type PidFile struct {
+	path string
+	file *os.File
+}
+
+// controlPidFile returns the path of the pid file for control command running
+// with scope.
+func controlPidFile(scope string) string {
+	return filepath.Join(viper.GetString("runtimedir"), "control-"+scope+".pid")
+}
+
+// AcquirePidFile locks the file at path and writes the pid of the calling
+// process. When some other running process holds the lock, the error wraps
+// ErrAlready.
+func AcquirePidFile(path string) (*PidFile, error) {
+	for {
+		file, err := lockPidFile(path)
+		switch {
+		case err != nil:
+			return nil, err
+		case file != nil:
+			return &PidFile{path: path, file: file}, nil
+		}
+		// removed by exiting instance before locked, try again
+	}
+}
+
+// lockPidFile opens and locks the file at path, then writes the pid. When the
+// file locked is no longer at path, it returns no file and no error.
+func lockPidFile(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("%w: pid file: %v", ErrFailure, err)
+	}
+	if err = unix.Flock(int(file.Fd()), unix.LOCK_EX|unix.LOCK_NB); err != nil {
+		file.Close()
+		if errors.Is(err, unix.EWOULDBLOCK) {
+			pid, _ := readPid(path)
+			return nil, fmt.Errorf("%w: pid %d: %s", ErrAlready, pid, path)
+		}
+		return nil, fmt.Errorf("%w: pid file: %v", ErrFailure, err)
+	}
+	var locked, current unix.Stat_t
+	if err = unix.Fstat(int(file.Fd()), &locked); err == nil {
+		if unix.Stat(path, &current) != nil || current.Ino != locked.Ino || current.Dev != locked.Dev {
+			file.Close()
+			return nil, nil
+		}
+		if err = file.Truncate(0); err == nil {
+			_, err = file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+		}
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%w: pid file: %v", ErrFailure, err)
+	}
+	return file, nil
+}
+
+// Release removes the file while still holding the lock, then releases the
+// lock. Some instance starting meanwhile finds that the file it locked was
+// removed, and tries again.
+func (pf *PidFile) Release() error {
+	if pf == nil {
+		return nil
+	}
+	os.Remove(pf.path)
+	return pf.file.Close() // and unlock
+}
+
+func readPid(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(string(bytes.TrimSpace(data)))
+}
+
+// RunningPid returns the pid written in the file at path, when the lock is
+// held. Otherwise the error wraps ErrNotFound.
+func RunningPid(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("%w: not running: %s", ErrNotFound, path)
+	}
+	defer file.Close()
+	if err = unix.Flock(int(file.Fd()), unix.LOCK_SH|unix.LOCK_NB); err == nil {
+		return 0, fmt.Errorf("%w: not running: stale %s", ErrNotFound, path)
+	}
+	pid, err := readPid(path)
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("%w: bad pid file: %s", ErrInvalid, path)
+	}
+	return pid, nil
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
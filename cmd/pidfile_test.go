@@ -0,0 +1,100 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "control-user.pid")
+	if _, err := RunningPid(path); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v without file, want %v", err, ErrNotFound)
+	}
+	pf, err := AcquirePidFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pid, err := RunningPid(path); err != nil || pid != os.Getpid() {
+		t.Errorf("got (%d, %v), want (%d, nil)", pid, err, os.Getpid())
+	}
+	if _, err := AcquirePidFile(path); !errors.Is(err, ErrAlready) {
+		t.Errorf("got error %v when locked, want %v", err, ErrAlready)
+	}
+	if err := pf.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if exists(path) {
+		t.Error("file not removed on release")
+	}
+	if _, err := RunningPid(path); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v after release, want %v", err, ErrNotFound)
+	}
+	var none *PidFile
+	if err := none.Release(); err != nil {
+		t.Errorf("got error %v releasing nil", err)
+	}
+}
+
+func TestPidFileContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		locked  bool
+		wantErr error
+	}{
+		{"stale", "1\n", false, ErrNotFound},
+		{"empty", "", false, ErrNotFound},
+		{"garbage", "nicy\n", true, ErrInvalid},
+		{"negative", "-1\n", true, ErrInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "control-user.pid")
+			if tt.locked {
+				pf, err := AcquirePidFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer pf.Release()
+			}
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := RunningPid(path); !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.locked {
+				return
+			}
+			// stale files are taken over
+			pf, err := AcquirePidFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pf.Release()
+			if pid, err := RunningPid(path); err != nil || pid != os.Getpid() {
+				t.Errorf("got (%d, %v) once acquired, want (%d, nil)", pid, err, os.Getpid())
+			}
+		})
+	}
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
@@ -0,0 +1,79 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// pinCmd represents the pin command
+var pinCmd = &cobra.Command{
+	Use:   "pin [--scope SCOPE] PID",
+	Short: "Exempt a process from control",
+	Long: `Stop applying any rule to the process group of PID, in the running control command
+
+The process group is exempted until the process exits or until the unpin
+command.`,
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		// Bind shared flags
+		if err := viper.BindPFlags(cmd.LocalNonPersistentFlags()); err != nil {
+			return err
+		}
+		return checkCtlScope()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		// Debug output
+		debugOutput(cmd)
+		// Real job goes here
+		sendCtlCommand(cmd, CtlRequest{Command: "pin", Pid: parsePid(args[0])})
+	},
+}
+
+// unpinCmd represents the unpin command
+var unpinCmd = &cobra.Command{
+	Use:   "unpin [--scope SCOPE] PID",
+	Short: "Control again a process",
+	Long: `Apply again its rule to the process group of PID, in the running control command
+
+The attributes pinned with pin command, or changed by hand since nicy applied
+them, are enforced again.`,
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		// Bind shared flags
+		if err := viper.BindPFlags(cmd.LocalNonPersistentFlags()); err != nil {
+			return err
+		}
+		return checkCtlScope()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		// Debug output
+		debugOutput(cmd)
+		// Real job goes here
+		sendCtlCommand(cmd, CtlRequest{Command: "unpin", Pid: parsePid(args[0])})
+	},
+}
+
+func init() {
+	addCtlScopeFlag(pinCmd)
+	addCtlScopeFlag(unpinCmd)
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
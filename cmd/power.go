@@ -0,0 +1,93 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Power sources
+const (
+	powerAC      = "ac"
+	powerBattery = "battery"
+)
+
+// powerSource is the last power source read.
+var powerSource struct {
+	mu     sync.Mutex
+	source string
+}
+
+// ReadPowerSource returns battery when some battery is found while no mains
+// or USB power supply is online, ac otherwise. Power supplies are read under
+// power.root key directory.
+func ReadPowerSource() string {
+	dir := filepath.Join(fsRoot("power.root"), "sys", "class", "power_supply")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		debug(err)
+		return powerAC
+	}
+	read := func(name, file string) string {
+		data, err := os.ReadFile(filepath.Join(dir, name, file))
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(data))
+	}
+	battery := false
+	for _, entry := range entries {
+		switch read(entry.Name(), "type") {
+		case "Mains", "USB":
+			if read(entry.Name(), "online") == "1" {
+				return powerAC
+			}
+		case "Battery":
+			battery = true
+		}
+	}
+	if battery {
+		return powerBattery
+	}
+	return powerAC
+}
+
+// CurrentPowerSource returns the power source, read once until updated.
+func CurrentPowerSource() string {
+	powerSource.mu.Lock()
+	defer powerSource.mu.Unlock()
+	if powerSource.source == "" {
+		powerSource.source = ReadPowerSource()
+	}
+	return powerSource.source
+}
+
+// UpdatePowerSource reads the power source again and returns it, with
+// whether it changed.
+func UpdatePowerSource() (string, bool) {
+	source := ReadPowerSource()
+	powerSource.mu.Lock()
+	defer powerSource.mu.Unlock()
+	changed := powerSource.source != "" && powerSource.source != source
+	powerSource.source = source
+	return source, changed
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
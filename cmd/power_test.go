@@ -0,0 +1,80 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// setPowerSource sets the power source until the test ends.
+func setPowerSource(t *testing.T, source string) {
+	t.Helper()
+	powerSource.mu.Lock()
+	previous := powerSource.source
+	powerSource.source = source
+	powerSource.mu.Unlock()
+	t.Cleanup(func() {
+		powerSource.mu.Lock()
+		powerSource.source = previous
+		powerSource.mu.Unlock()
+	})
+}
+
+func TestReadPowerSource(t *testing.T) {
+	// power supplies, as name and content of type and online files
+	type supply struct{ name, kind, online string }
+	tests := []struct {
+		name     string
+		supplies []supply
+		want     string
+	}{
+		{"none", nil, powerAC},
+		{"desktop", []supply{{"AC", "Mains", "1"}}, powerAC},
+		{"on battery", []supply{{"AC", "Mains", "0"}, {"BAT0", "Battery", ""}}, powerBattery},
+		{"on ac", []supply{{"AC", "Mains", "1"}, {"BAT0", "Battery", ""}}, powerAC},
+		{"on usb", []supply{{"BAT0", "Battery", ""}, {"ucsi", "USB", "1"}}, powerAC},
+		{"offline usb", []supply{{"BAT0", "Battery", ""}, {"ucsi", "USB", "0"}}, powerBattery},
+		{"battery only", []supply{{"BAT0", "Battery", ""}}, powerBattery},
+		{"device battery", []supply{{"hidpp", "Battery", ""}, {"AC", "Mains", "1"}}, powerAC},
+	}
+	defer viper.Set("power.root", viper.GetString("power.root"))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			for _, s := range tt.supplies {
+				dir := filepath.Join(root, "sys", "class", "power_supply", s.name)
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					t.Fatal(err)
+				}
+				os.WriteFile(filepath.Join(dir, "type"), []byte(s.kind+"\n"), 0644)
+				if s.online != "" {
+					os.WriteFile(filepath.Join(dir, "online"), []byte(s.online+"\n"), 0644)
+				}
+			}
+			viper.Set("power.root", root)
+			if got := ReadPowerSource(); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
@@ -92,6 +92,20 @@ func LoadConfig[T Preset](m map[string][]T, f func() chan T) {
 	}
 }
 
+// MergePresets copies into dst the presets of base, then those of other. Unless
+// override, the keys found in base are ignored in other.
+func MergePresets[T Preset](dst, base, other map[string][]T, override bool) {
+	for key, slice := range base {
+		dst[key] = append([]T(nil), slice...)
+	}
+	for key, slice := range other {
+		if _, found := base[key]; found && !(override) {
+			continue
+		}
+		dst[key] = append(dst[key], slice...)
+	}
+}
+
 type Struct interface {
 	BaseCgroup | Cgroup | BaseProfile | Profile | BaseRule | Rule
 }
@@ -146,6 +160,18 @@ func UpdateRule[T BaseStruct](st T, rule *Rule) {
 	}
 }
 
+// OverrideBase iterates through st fields and, if set, overrides matching
+// fields in base.
+func OverrideBase[T BaseStruct](st T, base *T) {
+	s := reflect.ValueOf(&st).Elem()
+	b := reflect.ValueOf(base).Elem()
+	for i := 0; i < s.NumField(); i++ {
+		if f := s.Field(i); f.IsValid() && !f.IsZero() {
+			b.Field(i).Set(f)
+		}
+	}
+}
+
 func Properties[T BaseStruct](st T) (result []string) {
 	base := reflect.ValueOf(&st).Elem()
 	typeOfBase := base.Type()
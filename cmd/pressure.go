@@ -0,0 +1,316 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// defaultWindow is the time pressure must stay above threshold, or below
+// recover value, before changing step.
+const defaultWindow = 30 * time.Second
+
+// PressureStep throttles the nicy slice for Cgroup key, when the pressure on
+// Resource stays above Threshold during Window, until it stays below Recover
+// during Window.
+type PressureStep struct {
+	Name      string        `mapstructure:"name" yaml:"name" json:"name"`
+	Resource  string        `mapstructure:"resource" yaml:"resource" json:"resource"`
+	Slice     string        `mapstructure:"slice" yaml:"slice,omitempty" json:"slice,omitempty"`
+	Kind      string        `mapstructure:"kind" yaml:"kind,omitempty" json:"kind,omitempty"`
+	Threshold float64       `mapstructure:"threshold" yaml:"threshold" json:"threshold"`
+	Recover   float64       `mapstructure:"recover" yaml:"recover,omitempty" json:"recover,omitempty"`
+	Window    time.Duration `mapstructure:"window" yaml:"window,omitempty" json:"window,omitempty"`
+	Cgroup    string        `mapstructure:"cgroup" yaml:"cgroup" json:"cgroup"`
+	CPUQuota  string        `mapstructure:"CPUQuota" yaml:"CPUQuota,omitempty" json:"CPUQuota,omitempty"`
+	IOWeight  string        `mapstructure:"IOWeight" yaml:"IOWeight,omitempty" json:"IOWeight,omitempty"`
+	Freeze    bool          `mapstructure:"freeze" yaml:"freeze,omitempty" json:"freeze,omitempty"`
+}
+
+// Validate checks the step and sets default values.
+func (s *PressureStep) Validate(pc *PresetCache) error {
+	switch s.Resource {
+	case "cpu", "io", "memory":
+	default:
+		return fmt.Errorf("%w: step %s: resource: %q", ErrInvalid, s.Name, s.Resource)
+	}
+	switch s.Kind {
+	case "":
+		s.Kind = "some"
+	case "some", "full":
+	default:
+		return fmt.Errorf("%w: step %s: kind: %q", ErrInvalid, s.Name, s.Kind)
+	}
+	if s.Threshold <= 0 || s.Threshold > 100 {
+		return fmt.Errorf("%w: step %s: threshold: %v", ErrInvalid, s.Name, s.Threshold)
+	}
+	switch {
+	case s.Recover == 0:
+		s.Recover = s.Threshold / 2
+	case s.Recover < 0 || s.Recover >= s.Threshold:
+		return fmt.Errorf("%w: step %s: recover: %v", ErrInvalid, s.Name, s.Recover)
+	}
+	if s.Window <= 0 {
+		s.Window = defaultWindow
+	}
+	if !(pc.HasPreset("cgroup", s.Cgroup)) {
+		return fmt.Errorf("%w: step %s: cgroup %q", ErrNotFound, s.Name, s.Cgroup)
+	}
+	if s.CPUQuota == "" && s.IOWeight == "" && !(s.Freeze) {
+		return fmt.Errorf("%w: step %s: nothing to do", ErrInvalid, s.Name)
+	}
+	return nil
+}
+
+// Pressure returns the average pressure for the last 10 seconds, system wide
+// or inside the nicy slices for Slice key, the highest one when many.
+func (s *PressureStep) Pressure() (float64, error) {
+	if s.Slice == "" {
+		p, err := SystemPressure(s.Resource)
+		return p.Line(s.Kind).Avg10, err
+	}
+	var (
+		result float64
+		err    error
+		read   bool
+	)
+	for _, path := range nicySliceCgroups(s.Slice) {
+		p, failure := CgroupPressure(path, s.Resource)
+		if failure != nil {
+			if err == nil {
+				err = failure
+			}
+			continue
+		}
+		result, read = math.Max(result, p.Line(s.Kind).Avg10), true
+	}
+	if read {
+		return result, nil
+	}
+	return 0, err
+}
+
+// stepState remembers since when pressure crossed the limits of a step.
+type stepState struct {
+	Active bool
+	since  time.Time // above threshold, or below recover value when active
+}
+
+// Throttler applies the pressure steps.
+type Throttler struct {
+	mu     sync.Mutex
+	steps  []PressureStep
+	states []stepState
+}
+
+// NewThrottler returns the throttler for the steps found in pressure.steps
+// key.
+func NewThrottler(pc *PresetCache) (*Throttler, error) {
+	return newThrottler(pc, viper.GetViper())
+}
+
+func newThrottler(pc *PresetCache, v *viper.Viper) (*Throttler, error) {
+	var steps []PressureStep
+	if err := v.UnmarshalKey("pressure.steps", &steps); err != nil {
+		return nil, fmt.Errorf("%w: pressure steps: %v", ErrInvalid, err)
+	}
+	for i := range steps {
+		if steps[i].Name == "" {
+			steps[i].Name = fmt.Sprintf("%s-%d", steps[i].Cgroup, i)
+		}
+		if err := steps[i].Validate(pc); err != nil {
+			return nil, err
+		}
+	}
+	return &Throttler{steps: steps, states: make([]stepState, len(steps))}, nil
+}
+
+// Update reads the pressure for each step, then throttles or reverts the
+// nicy slices when pressure stayed long enough above threshold or below
+// recover value. The keys of the cgroups changed are returned.
+func (t *Throttler) Update(pc *PresetCache, now time.Time) (changed []string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := range t.steps {
+		step, state := &t.steps[i], &t.states[i]
+		value, err := step.Pressure()
+		if err != nil {
+			debug(err)
+			continue
+		}
+		crossed := value >= step.Threshold
+		if state.Active {
+			crossed = value <= step.Recover
+		}
+		switch {
+		case !(crossed):
+			state.since = time.Time{}
+		case state.since.IsZero():
+			state.since = now
+		}
+		if crossed && now.Sub(state.since) >= step.Window {
+			state.Active, state.since = !(state.Active), time.Time{}
+			action := "reverting"
+			if state.Active {
+				action = "throttling"
+			}
+			inform(step.Name, fmt.Sprintf(
+				"%s pressure %.2f%%, %s cgroup %s", step.Resource, value, action, step.Cgroup,
+			))
+			if err := t.apply(pc, step); err != nil {
+				warn(err)
+			}
+			changed = append(changed, step.Cgroup)
+		}
+	}
+	return
+}
+
+// apply sets the nicy slice for Cgroup key of the step, from its cgroup
+// preset and the properties of the active steps for the same key, in order.
+// The slice is frozen while some active step requires it.
+func (t *Throttler) apply(pc *PresetCache, step *PressureStep) (err error) {
+	var (
+		key                    = step.Cgroup
+		preset, _              = pc.Cgroup(key)
+		target                 BaseCgroup
+		quota, weight, freezer bool
+		frozen                 bool
+	)
+	target.CPUQuota, target.IOWeight = preset.CPUQuota, preset.IOWeight
+	for i, other := range t.steps {
+		if other.Cgroup != key {
+			continue
+		}
+		quota = quota || other.CPUQuota != ""
+		weight = weight || other.IOWeight != ""
+		freezer = freezer || other.Freeze
+		if !(t.states[i].Active) {
+			continue
+		}
+		if other.CPUQuota != "" {
+			target.CPUQuota = other.CPUQuota
+		}
+		if other.IOWeight != "" {
+			target.IOWeight = other.IOWeight
+		}
+		frozen = frozen || other.Freeze
+	}
+	unit := "nicy-" + key + ".slice"
+	var properties []string
+	if quota && target.CPUQuota == "" {
+		properties = append(properties, "CPUQuota=") // reset
+	}
+	if weight && target.IOWeight == "" {
+		properties = append(properties, "IOWeight=") // reset
+	}
+	if !(quota) {
+		target.CPUQuota = ""
+	}
+	if !(weight) {
+		target.IOWeight = ""
+	}
+	properties = append(properties, target.ScopeProperties()...)
+	err = SetSliceProperties(step.Name, key, properties)
+	if freezer {
+		if viper.GetBool("dry-run") || viper.GetBool("verbose") {
+			inform(step.Name, fmt.Sprintf("%s: frozen: %v", unit, frozen))
+		}
+		if !(viper.GetBool("dry-run")) {
+			if failure := freezeNicySlices(key, frozen); failure != nil && err == nil {
+				err = fmt.Errorf("%w: %s: %v", ErrFailure, unit, failure)
+			}
+		}
+	}
+	return
+}
+
+// Reapply applies again the active steps for cgroup key, once the properties
+// of its nicy slice changed.
+func (t *Throttler) Reapply(pc *PresetCache, key string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := range t.steps {
+		if t.steps[i].Cgroup == key && t.states[i].Active {
+			nonfatal(t.apply(pc, &t.steps[i]))
+			return
+		}
+	}
+}
+
+// Revert reverts all the throttled nicy slices.
+func (t *Throttler) Revert(pc *PresetCache) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.revert(pc)
+}
+
+func (t *Throttler) revert(pc *PresetCache) {
+	var active []int
+	for i := range t.states {
+		if t.states[i].Active {
+			active = append(active, i)
+		}
+		t.states[i] = stepState{}
+	}
+	for _, i := range active {
+		nonfatal(t.apply(pc, &t.steps[i]))
+	}
+}
+
+// Replace reverts all the throttled nicy slices, then applies the steps of
+// next throttler instead.
+func (t *Throttler) Replace(pc *PresetCache, next *Throttler) {
+	if t == nil || next == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.revert(pc)
+	t.steps, t.states = next.steps, next.states
+}
+
+// Throttled returns the names of the active steps.
+func (t *Throttler) Throttled() (result []string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, state := range t.states {
+		if state.Active {
+			result = append(result, t.steps[i].Name)
+		}
+	}
+	return
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
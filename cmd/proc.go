@@ -4,16 +4,17 @@ package cmd
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/user"
-	"path/filepath"
 	"reflect"
 	"runtime"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 	"unsafe"
 
 	"golang.org/x/sys/unix"
@@ -152,7 +153,7 @@ func Sched_GetParam(pid int) (int, error) {
 const userHZ = 100
 
 func GetResource(pid int, rc string) ([]byte, error) {
-	return os.ReadFile(fmt.Sprintf("/proc/%d/%s", pid, rc))
+	return procFS.Resource(pid, rc)
 }
 
 // % cat /proc/$(pidof nvim)/stat
@@ -214,29 +215,169 @@ type ProcStat struct {
 	stat                string `json:"-"`
 }
 
-func (stat *ProcStat) UnmarshalText(buffer []byte) (err error) {
-	stat.stat = strings.TrimSpace(string(buffer))
-	rv := reflect.ValueOf(stat).Elem()
-	fields := strings.Split(stat.stat, " ")
-	for i := 0; i < (rv.NumField() - 1); i++ {
-		switch f := rv.Field(i); f.Kind() {
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			v, err := strconv.ParseInt(fields[i], 0, f.Type().Bits())
-			if err != nil {
-				return err
-			}
-			f.SetInt(v)
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			v, err := strconv.ParseUint(fields[i], 0, f.Type().Bits())
-			if err != nil {
-				return err
-			}
-			f.SetUint(v)
-		case reflect.String:
-			f.SetString(fields[i])
-		}
+// minStatFields is the number of fields that stat files hold at least, up to
+// policy (41).
+const minStatFields = 41
+
+var (
+	errStatComm  = errors.New("no command name")
+	errStatShort = errors.New("too few fields")
+)
+
+// StatError reports some malformed stat line.
+type StatError struct {
+	Pid   int    // when parsed
+	Field int    // number of the field, from (1)
+	Value string // when unparsable
+	Err   error
+}
+
+func (e *StatError) Error() string {
+	msg := fmt.Sprintf("%v: stat", ErrParse)
+	if e.Pid > 0 {
+		msg += fmt.Sprintf(": pid %d", e.Pid)
+	}
+	msg += fmt.Sprintf(": field (%d)", e.Field)
+	if e.Value != "" {
+		msg += fmt.Sprintf(": %q", e.Value)
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+func (e *StatError) Unwrap() error {
+	return e.Err
+}
+
+// Is makes any StatError match ErrParse.
+func (e *StatError) Is(target error) bool {
+	return target == error(ErrParse)
+}
+
+// statDecoder decodes the fields following the command name, in order. Once
+// some field fails, the next ones are left to zero.
+type statDecoder struct {
+	fields []string
+	field  int // number of the next field
+	err    *StatError
+}
+
+func (d *statDecoder) next() (string, bool) {
+	i := d.field - 3
+	d.field++
+	if d.err != nil || i >= len(d.fields) { // missing on older kernels
+		return "", false
+	}
+	return d.fields[i], true
+}
+
+func (d *statDecoder) fail(value string, err error) {
+	d.err = &StatError{Field: d.field - 1, Value: value, Err: err}
+}
+
+func (d *statDecoder) string() string {
+	s, _ := d.next()
+	return s
+}
+
+func (d *statDecoder) int() int {
+	s, ok := d.next()
+	if !(ok) {
+		return 0
+	}
+	v, err := strconv.ParseInt(s, 10, strconv.IntSize)
+	if err != nil {
+		d.fail(s, err)
+	}
+	return int(v)
+}
+
+func (d *statDecoder) uint() uint {
+	return uint(d.uint64(strconv.IntSize))
+}
+
+func (d *statDecoder) uint64(bits int) uint64 {
+	s, ok := d.next()
+	if !(ok) {
+		return 0
+	}
+	v, err := strconv.ParseUint(s, 10, bits)
+	if err != nil {
+		d.fail(s, err)
+	}
+	return v
+}
+
+// UnmarshalText parses some stat line. The command name, that may hold
+// spaces and parentheses, ends at the last closing parenthesis.
+func (stat *ProcStat) UnmarshalText(buffer []byte) error {
+	line := strings.TrimSpace(string(buffer))
+	open, end := strings.IndexByte(line, '('), strings.LastIndexByte(line, ')')
+	if open < 0 || end < open {
+		return &StatError{Field: 2, Err: errStatComm}
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(line[:open]))
+	if err != nil {
+		return &StatError{Field: 1, Value: line[:open], Err: err}
+	}
+	*stat = ProcStat{Pid: pid, Comm: line[open+1 : end], stat: line}
+	d := statDecoder{fields: strings.Fields(line[end+1:]), field: 3}
+	if n := len(d.fields) + 2; n < minStatFields {
+		return &StatError{Pid: pid, Field: n + 1, Err: errStatShort}
+	}
+	stat.State = d.string()
+	stat.Ppid = d.int()
+	stat.Pgrp = d.int()
+	stat.Session = d.int()
+	stat.TtyNr = d.int()
+	stat.TPGid = d.int()
+	stat.Flags = d.uint()
+	stat.MinFlt = d.uint()
+	stat.CMinFlt = d.uint()
+	stat.MajFlt = d.uint()
+	stat.CMajFlt = d.uint()
+	stat.UTime = d.uint()
+	stat.STime = d.uint()
+	stat.CUTime = d.int()
+	stat.CSTime = d.int()
+	stat.Priority = d.int()
+	stat.Nice = d.int()
+	stat.NumThreads = d.int()
+	stat.ITRealValue = d.int()
+	stat.StartTime = d.uint64(64)
+	stat.VSize = d.uint()
+	stat.Rss = d.int()
+	stat.RssLim = d.uint()
+	stat.StartCode = d.uint()
+	stat.EndCode = d.uint()
+	stat.StartStack = d.uint()
+	stat.KStkESP = d.uint()
+	stat.KStkEIP = d.uint()
+	stat.Signal = d.uint()
+	stat.Blocked = d.uint()
+	stat.SigIgnore = d.uint()
+	stat.SigCatch = d.uint()
+	stat.WChan = d.uint()
+	stat.NSwap = d.uint()
+	stat.CNSwap = d.uint()
+	stat.ExitSignal = d.int()
+	stat.Processor = d.int()
+	stat.RTPrio = d.int()
+	stat.Policy = d.int()
+	stat.DelayAcctBlkIOTicks = d.uint64(64)
+	stat.GuestTime = d.uint()
+	stat.CGuestTime = d.int()
+	stat.StartData = d.uint()
+	stat.EndData = d.uint()
+	stat.StartBrk = d.uint()
+	stat.ArgStart = d.uint()
+	stat.ArgEnd = d.uint()
+	stat.EnvStart = d.uint()
+	stat.EnvEnd = d.uint()
+	stat.ExitCode = d.int()
+	if d.err != nil {
+		d.err.Pid = pid
+		return d.err
 	}
-	stat.Comm = strings.Trim(stat.Comm, "()")
 	return nil
 }
 
@@ -269,25 +410,75 @@ func (stat *ProcStat) String() string {
 	return "{" + strings.Join(s, ", ") + "}"
 }
 
+// RuleName returns the name of the rule for the command name.
+func (stat *ProcStat) RuleName() string {
+	return strings.Split(stat.Comm, `:`)[0]
+}
+
 // CPUTime returns the total CPU user and system time in seconds.
 func (stat *ProcStat) CPUTime() float64 {
 	return float64(stat.UTime+stat.STime) / userHZ
 }
 
+// Age returns the time elapsed since the process started.
+func (stat *ProcStat) Age(uptime float64) time.Duration {
+	seconds := uptime - float64(stat.StartTime)/userHZ
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// Uptime returns the time elapsed since the system booted, in seconds.
+func Uptime() (uptime float64, err error) {
+	data, err := os.ReadFile(procFS.Path("uptime"))
+	if err != nil {
+		return
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("%w: uptime: %q", ErrInvalid, data)
+	}
+	return strconv.ParseFloat(fields[0], 64)
+}
+
 func GetStat(path string) (stat unix.Stat_t, err error) {
 	err = unix.Stat(path, &stat)
 	return
 }
 
 func GetUid(pid int) int {
-	if stat, err := GetStat(fmt.Sprintf("/proc/%d", pid)); err == nil {
-		return int(stat.Uid)
-	}
-	return -1
+	return procFS.Uid(pid)
 }
 
+// unknownUserDelay is the time after which some unknown user is looked up
+// again.
+const unknownUserDelay = time.Minute
+
+// knownUser is some user looked up, or not found, at some time.
+type knownUser struct {
+	user  *user.User
+	err   error
+	since time.Time
+}
+
+// knownUsers memoizes the users looked up by uid.
+var knownUsers = struct {
+	sync.RWMutex
+	users map[int]knownUser
+}{users: make(map[int]knownUser)}
+
+// GetUser returns the user with uid, looking up the user only once, unless
+// not found.
 func GetUser(uid int) (*user.User, error) {
-	return user.LookupId(strconv.Itoa(uid))
+	knownUsers.RLock()
+	known, found := knownUsers.users[uid]
+	knownUsers.RUnlock()
+	if found && (known.err == nil || time.Since(known.since) < unknownUserDelay) {
+		return known.user, known.err
+	}
+	u, err := user.LookupId(strconv.Itoa(uid))
+	knownUsers.Lock()
+	knownUsers.users[uid] = knownUser{user: u, err: err, since: time.Now()}
+	knownUsers.Unlock()
+	return u, err
 }
 
 func GetCgroup(pid int) (cgroup string, err error) {
@@ -306,21 +497,40 @@ func GetOomScoreAdj(pid int) (score int, err error) {
 
 type Proc struct {
 	ProcStat
-	Uid         int       `json:"uid"`
-	owner       user.User `json:"-"`
-	Cgroup      string    `json:"cgroup"`
-	Slice       string    `json:"slice"`
-	Unit        string    `json:"unit"`
-	OomScoreAdj int       `json:"oom_score_adj"`
-	IOPrioClass int       `json:"ioprio_class"`
-	IOPrioData  int       `json:"ionice"`
+	Uid          int       `json:"uid"`
+	Cgroup       string    `json:"cgroup"`
+	Slice        string    `json:"slice"`
+	Unit         string    `json:"unit"`
+	OomScoreAdj  int       `json:"oom_score_adj"`
+	IOPrioClass  int       `json:"ioprio_class"`
+	IOPrioData   int       `json:"ionice"`
+	Frozen       bool      `json:"frozen,omitempty"` // only read on dump
+	*ProcDetails           // only read on dump, when required
+	Threads      []*Thread `json:"threads,omitempty"` // only read on dump, when required
+	rule         string    // inherited or shared within process group
+	loaded       bool      // other attributes read, see Load
+}
+
+// RuleName returns the name of the rule applying to the process, which is,
+// unless inherited or shared, its command name.
+func (p *Proc) RuleName() string {
+	if p.rule != "" {
+		return p.rule
+	}
+	return p.ProcStat.RuleName()
 }
 
-func (p *Proc) setUser() (err error) {
-	p.Uid = GetUid(p.Pid)
+// Owner returns the owner of the process, looked up once per uid, or some
+// empty user when unknown.
+func (p *Proc) Owner() *user.User {
 	if owner, err := GetUser(p.Uid); err == nil {
-		p.owner = *owner
+		return owner
 	}
+	return &user.User{}
+}
+
+func (p *Proc) setUser() (err error) {
+	p.Uid = GetUid(p.Pid)
 	return
 }
 
@@ -342,7 +552,18 @@ func (p *Proc) setOomScoreAdj() (err error) {
 	return
 }
 
+// setFrozen reads whether the cgroup of the process is frozen.
+func (p *Proc) setFrozen() (err error) {
+	if path := strings.TrimPrefix(p.Cgroup, "0::/"); path != "" {
+		p.Frozen, err = CgroupFrozen(path)
+	}
+	return
+}
+
 func (p *Proc) setIOPrio() (err error) {
+	if !(procFS.Live()) { // no such file
+		return
+	}
 	if ioprio, err := IOPrio_Get(p.Pid); err == nil {
 		IOPrio_Split(ioprio, &p.IOPrioClass, &p.IOPrioData)
 	}
@@ -351,8 +572,29 @@ func (p *Proc) setIOPrio() (err error) {
 
 type setter = func() error
 
+// setters returns the setters of the attributes that filters may use.
 func (p *Proc) setters() []setter {
-	return []setter{p.setUser, p.setCgroup, p.setOomScoreAdj, p.setIOPrio}
+	return []setter{p.setUser, p.setCgroup}
+}
+
+// loaders returns the setters of the other attributes, only read for the
+// processes that passed the filter.
+func (p *Proc) loaders() []setter {
+	return []setter{p.setOomScoreAdj, p.setIOPrio}
+}
+
+// Load reads the other attributes of the process, once, unless yet read.
+func (p *Proc) Load() error {
+	if p.loaded {
+		return nil
+	}
+	for _, function := range p.loaders() {
+		if err := function(); err != nil {
+			return err
+		}
+	}
+	p.loaded = true
+	return nil
 }
 
 func NewProc(pid int) *Proc {
@@ -365,6 +607,9 @@ func NewProc(pid int) *Proc {
 			panic(err)
 		}
 	}
+	if err := p.Load(); err != nil {
+		panic(err)
+	}
 	return p
 }
 
@@ -382,15 +627,48 @@ func NewProcFromStat(stat []byte) (p *Proc, err error) {
 	}
 	for _, function := range p.setters() {
 		if err = function(); err != nil {
-			break
+			return
 		}
 	}
+	err = p.Load()
 	return
 }
 
+// scanProc returns the process for stat data, when it passes the filter. The
+// stat fields are first checked, when the filter allows, then the attributes
+// that the filter may use are read. The other attributes are only read once
+// the process passed, unless the filter leaves them to Load.
+func scanProc(stat []byte, filter Filterer[Proc]) (*Proc, bool) {
+	p := new(Proc)
+	// Stat
+	err := p.ProcStat.UnmarshalText(stat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+	} else if f, ok := filter.(StatFilterer); ok && !(f.FilterStat(&p.ProcStat)) {
+		return nil, false
+	}
+	if err == nil {
+		for _, function := range p.setters() {
+			if err = function(); err != nil {
+				break
+			}
+		}
+	}
+	if !(filter.Filter(p, err)) {
+		return nil, false
+	}
+	if f, ok := filter.(LazyFilterer); ok && f.LoadLater() {
+		return p, true
+	}
+	if err := p.Load(); err != nil {
+		return nil, false
+	}
+	return p, true
+}
+
 func (p *Proc) entries() string {
-	return fmt.Sprintf("Uid: %v, owner: %+v, Cgroup: %v, Slice: %v, Unit: %v, RTPrio: %v, Policy: %v, OomScoreAdj: %v, IOPrioData: %v, IOPrioClass: %v",
-		p.Uid, p.owner, p.Cgroup, p.Slice, p.Unit, p.RTPrio, p.Policy, p.OomScoreAdj, p.IOPrioData, p.IOPrioClass,
+	return fmt.Sprintf("Uid: %v, owner: %+v, Cgroup: %v, Slice: %v, Unit: %v, RTPrio: %v, Policy: %v, OomScoreAdj: %v, IOPrioData: %v, IOPrioClass: %v, Frozen: %v",
+		p.Uid, *p.Owner(), p.Cgroup, p.Slice, p.Unit, p.RTPrio, p.Policy, p.OomScoreAdj, p.IOPrioData, p.IOPrioClass, p.Frozen,
 	)
 }
 
@@ -412,27 +690,29 @@ func (p Proc) Json() (result string) {
 }
 
 func (p Proc) Raw() string {
-	return strings.TrimSuffix(
-		fmt.Sprintln(
-			p.Pid,
-			p.Ppid,
-			p.Pgrp,
-			p.Uid,
-			p.Username(),
-			p.State,
-			p.Priority,
-			p.Nice,
-			p.NumThreads,
-			p.RTPrio,
-			p.Policy,
-			p.OomScoreAdj,
-			p.IOPrioClass,
-			p.IOPrioData,
-			p.Comm,
-			p.Cgroup,
-		),
-		"\n",
-	)
+	columns := []interface{}{
+		p.Pid,
+		p.Ppid,
+		p.Pgrp,
+		p.Uid,
+		p.Username(),
+		p.State,
+		p.Priority,
+		p.Nice,
+		p.NumThreads,
+		p.RTPrio,
+		p.Policy,
+		p.OomScoreAdj,
+		p.IOPrioClass,
+		p.IOPrioData,
+		p.Comm,
+		p.Cgroup,
+		p.Frozen,
+	}
+	if p.ProcDetails != nil {
+		columns = append(columns, p.ProcDetails.columns()...)
+	}
+	return strings.TrimSuffix(fmt.Sprintln(columns...), "\n")
 }
 
 func (p *Proc) Sched() string {
@@ -467,7 +747,8 @@ func (p *Proc) Runtime() BaseProfile {
 }
 
 func (p *Proc) Values() string {
-	return fmt.Sprintf("[%d,%d,%d,%d,%q,%q,%q,%q,%q,%q,%d,%d,%d,%d,%d,%d,%q,%d]",
+	format := "%d,%d,%d,%d,%q,%q,%q,%q,%q,%q,%d,%d,%d,%d,%d,%d,%q,%d,%t"
+	columns := []interface{}{
 		p.Pid,
 		p.Ppid,
 		p.Pgrp,
@@ -486,7 +767,13 @@ func (p *Proc) Values() string {
 		p.OomScoreAdj,
 		p.IOClass(),
 		p.IOPrioData,
-	)
+		p.Frozen,
+	}
+	if p.ProcDetails != nil {
+		format += ",%q,%d,%d,%d,%q,%d,%d,%d,%d,%d,%q"
+		columns = append(columns, p.ProcDetails.columns()...)
+	}
+	return fmt.Sprintf("["+format+"]", columns...)
 }
 
 func (p *Proc) GetStringMap() (result map[string]interface{}) {
@@ -499,7 +786,7 @@ func (p *Proc) GetStringMap() (result map[string]interface{}) {
 }
 
 func (p *Proc) Username() string {
-	return p.owner.Username
+	return p.Owner().Username
 }
 
 func (p *Proc) InUserSlice() bool {
@@ -534,38 +821,56 @@ func (s ProcByPid) Less(i, j int) bool { return s[i].Pid < s[j].Pid }
 
 // FilteredProcs returns a slice of Proc for filtered processes.
 func FilteredProcs(filter Filterer[Proc]) (result []*Proc) {
-	files, err := filepath.Glob("/proc/[0-9]*/stat")
+	files, err := procFS.StatFiles()
 	if err != nil {
 		return
 	}
 	// make our channels for communicating work and results
-	stats := make(chan []byte, len(files))
-	// spin up workers and use a sync.WaitGroup to indicate completion
+	paths := make(chan string, len(files))
+	for _, file := range files {
+		paths <- file
+	}
+	close(paths)
+	// spin up workers, each collecting its own results, and use a
+	// sync.WaitGroup to indicate completion
+	found := make([][]*Proc, runtime.GOMAXPROCS(0))
 	var wg sync.WaitGroup
-	for i := 0; i < runtime.GOMAXPROCS(0); i++ {
+	for i := range found {
 		wg.Add(1)
-		go func() {
+		go func(i int) {
 			defer wg.Done()
-			for stat := range stats {
-				if p, err := NewProcFromStat(stat); filter.Filter(p, err) {
-					result = append(result, p)
+			for path := range paths {
+				data, err := os.ReadFile(path)
+				if err != nil { // process is gone
+					continue
+				}
+				if p, ok := scanProc(bytes.TrimSpace(data), filter); ok {
+					found[i] = append(found[i], p)
 				}
 			}
-		}()
-	}
-	// start sending jobs
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		for _, file := range files {
-			if data, err := os.ReadFile(file); err == nil {
-				stats <- bytes.TrimSpace(data)
-			}
-		}
-		close(stats)
-	}()
+		}(i)
+	}
 	// wait on the workers to finish
 	wg.Wait()
+	for _, procs := range found {
+		result = append(result, procs...)
+	}
+	// sort by Pid
+	sort.Sort(ProcByPid(result))
+	return
+}
+
+// SelectedProcs returns a slice of Proc for given and filtered processes.
+func SelectedProcs(pids []int, filter Filterer[Proc]) (result []*Proc) {
+	for _, pid := range pids {
+		data, err := GetResource(pid, "stat")
+		if err != nil { // process is gone
+			continue
+		}
+		if p, ok := scanProc(bytes.TrimSpace(data), filter); ok {
+			result = append(result, p)
+		}
+	}
 	// sort by Pid
 	sort.Sort(ProcByPid(result))
 	return
@@ -583,6 +888,18 @@ type Filterer[P Proc | ProcStat] interface {
 
 type ProcFilterer = Filterer[Proc]
 
+// StatFilterer is implemented by the filters that can first reject processes
+// from their stat fields, before reading anything else.
+type StatFilterer interface {
+	FilterStat(stat *ProcStat) bool
+}
+
+// LazyFilterer is implemented by the filters that leave the other attributes
+// of the processes to Load, when only some of them require these attributes.
+type LazyFilterer interface {
+	LoadLater() bool
+}
+
 type FilterAny[T any] struct {
 	Filter  func(p *T, err error) bool
 	Message string
@@ -592,12 +909,22 @@ type FilterProc = FilterAny[Proc]
 type ProcFilter struct {
 	FilterProc
 	Scope string
+	Stat  func(stat *ProcStat) bool // cheap prefilter, if any
+	Lazy  bool                      // other attributes left to Load
 }
 
 func (pf ProcFilter) Filter(p *Proc, err error) bool {
 	return pf.FilterProc.Filter(p, err)
 }
 
+func (pf ProcFilter) FilterStat(stat *ProcStat) bool {
+	return pf.Stat == nil || pf.Stat(stat)
+}
+
+func (pf ProcFilter) LoadLater() bool {
+	return pf.Lazy
+}
+
 func (pf ProcFilter) String() string {
 	return pf.Message
 }
@@ -647,7 +974,13 @@ func GetFilterer(scope string) Filterer[Proc] {
 	}
 }
 
-var GetScopeOnlyFilterer = GetFilterer
+// GetScopeOnlyFilterer returns the filter of the processes in scope, leaving
+// their other attributes to Load, once they are known to get some job.
+func GetScopeOnlyFilterer(scope string) Filterer[Proc] {
+	pf := GetFilterer(scope).(ProcFilter)
+	pf.Lazy = true
+	return pf
+}
 
 // ProcByPgrp implements sort.Interface for []*Proc based on Pgrp field
 type ProcByPgrp []*Proc
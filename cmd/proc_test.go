@@ -0,0 +1,165 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// statLine returns some stat line of 52 fields, for the process running
+// comm, with nice and policy values.
+func statLine(comm string, nice, policy int) string {
+	return fmt.Sprintf(
+		"4243 (%s) S 1 4243 4243 34817 4243 4194560 7183 0 12 0 "+
+			"146 38 0 0 20 %d 3 0 11052 227651584 5812 18446744073709551615 "+
+			"94733012520960 94733013165737 140723306016912 0 0 0 0 4096 134234626 "+
+			"0 0 0 17 2 0 %d 0 0 0 94733013395920 94733013433608 94733031956480 "+
+			"140723306022401 140723306022432 140723306022432 140723306024939 0",
+		comm, nice, policy,
+	)
+}
+
+// reflectUnmarshalText is the former parser of stat lines, splitting them on
+// spaces and setting the fields through reflection. Kept for benchmark only.
+func reflectUnmarshalText(stat *ProcStat, buffer []byte) (err error) {
+	stat.stat = strings.TrimSpace(string(buffer))
+	rv := reflect.ValueOf(stat).Elem()
+	fields := strings.Split(stat.stat, " ")
+	for i := 0; i < (rv.NumField() - 1); i++ {
+		switch f := rv.Field(i); f.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			v, err := strconv.ParseInt(fields[i], 0, f.Type().Bits())
+			if err != nil {
+				return err
+			}
+			f.SetInt(v)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			v, err := strconv.ParseUint(fields[i], 0, f.Type().Bits())
+			if err != nil {
+				return err
+			}
+			f.SetUint(v)
+		case reflect.String:
+			f.SetString(fields[i])
+		}
+	}
+	stat.Comm = strings.Trim(stat.Comm, "()")
+	return nil
+}
+
+func BenchmarkUnmarshalText(b *testing.B) {
+	line := []byte(statLine("firefox", 5, 0))
+	b.Run("reflect", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var stat ProcStat
+			if err := reflectUnmarshalText(&stat, line); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("decoder", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var stat ProcStat
+			if err := stat.UnmarshalText(line); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestUnmarshalTextSameAsReflect(t *testing.T) {
+	line := []byte(statLine("firefox", 5, 2))
+	var got, want ProcStat
+	if err := got.UnmarshalText(line); err != nil {
+		t.Fatal(err)
+	}
+	if err := reflectUnmarshalText(&want, line); err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestUnmarshalText(t *testing.T) {
+	tests := []struct {
+		name   string
+		line   string
+		comm   string
+		nice   int
+		policy int
+	}{
+		{"plain", statLine("firefox", 5, 0), "firefox", 5, 0},
+		{"spaces", statLine("Web Content", -3, 0), "Web Content", -3, 0},
+		{"parenthesis", statLine("a) (b", 0, 2), "a) (b", 0, 2},
+		{"trailing parenthesis", statLine("Web Content) (x)", 19, 5), "Web Content) (x)", 19, 5},
+		{"empty", statLine("", 0, 1), "", 0, 1},
+		{"older kernel", strings.Join(strings.Fields(statLine("vim", 1, 0))[:44], " "), "vim", 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stat ProcStat
+			if err := stat.UnmarshalText([]byte(tt.line)); err != nil {
+				t.Fatal(err)
+			}
+			if stat.Pid != 4243 || stat.Comm != tt.comm || stat.State != "S" ||
+				stat.Ppid != 1 || stat.Nice != tt.nice || stat.Policy != tt.policy {
+				t.Errorf("got pid %d, comm %q, state %s, ppid %d, nice %d, policy %d",
+					stat.Pid, stat.Comm, stat.State, stat.Ppid, stat.Nice, stat.Policy)
+			}
+		})
+	}
+}
+
+func TestUnmarshalTextErrors(t *testing.T) {
+	fields := strings.Fields(statLine("firefox", 5, 0))
+	tests := []struct {
+		name  string
+		line  string
+		field int
+	}{
+		{"no command", "4243 firefox S 1", 2},
+		{"unclosed command", "4243 (firefox S 1", 2},
+		{"bad pid", "pid (firefox) S 1", 1},
+		{"truncated", strings.Join(fields[:20], " "), 21},
+		{"truncated before policy", strings.Join(fields[:40], " "), 41},
+		{"bad number", strings.Replace(statLine("firefox", 5, 0), " 20 5 ", " 20 x ", 1), 19},
+		{"negative unsigned", strings.Replace(statLine("firefox", 5, 0), " 146 ", " -146 ", 1), 14},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stat ProcStat
+			err := stat.UnmarshalText([]byte(tt.line))
+			if !(errors.Is(err, ErrParse)) {
+				t.Fatalf("got %v, want ErrParse", err)
+			}
+			var statErr *StatError
+			if !(errors.As(err, &statErr)) || statErr.Field != tt.field {
+				t.Errorf("got %v, want field (%d)", err, tt.field)
+			}
+		})
+	}
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
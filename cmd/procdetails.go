@@ -0,0 +1,167 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ProcDetails holds the optional data read from cmdline, exe, status, io and
+// schedstat files, for diagnosis. The files that can not be read, like those
+// of the processes of other users, are left to zero values.
+type ProcDetails struct {
+	Cmdline                  []string `json:"cmdline"`
+	Exe                      string   `json:"exe"`
+	VmRSS                    uint64   `json:"vm_rss"` // in bytes
+	VoluntaryCtxtSwitches    uint64   `json:"voluntary_ctxt_switches"`
+	NonvoluntaryCtxtSwitches uint64   `json:"nonvoluntary_ctxt_switches"`
+	CpusAllowedList          string   `json:"cpus_allowed_list"`
+	ReadBytes                uint64   `json:"read_bytes"`
+	WriteBytes               uint64   `json:"write_bytes"`
+	RunTime                  uint64   `json:"run_time"`  // on CPU, in nanoseconds
+	WaitTime                 uint64   `json:"wait_time"` // on runqueue, in nanoseconds
+	Timeslices               uint64   `json:"timeslices"`
+}
+
+// readCmdline reads the arguments of the process, empty for kernel threads.
+func (d *ProcDetails) readCmdline(pid int) error {
+	data, err := GetResource(pid, "cmdline")
+	if err != nil {
+		return err
+	}
+	if data = bytes.TrimRight(data, "\x00"); len(data) > 0 {
+		d.Cmdline = strings.Split(string(data), "\x00")
+	}
+	return nil
+}
+
+// readExe reads the path of the executable of the process.
+func (d *ProcDetails) readExe(pid int) (err error) {
+	d.Exe, err = os.Readlink(procFS.Path(strconv.Itoa(pid), "exe"))
+	return
+}
+
+// scanFields calls fn with the key and the value of each line of the file rc
+// of the process, like "VmRSS:	  1024 kB".
+func scanFields(pid int, rc string, fn func(key string, value string) error) error {
+	data, err := GetResource(pid, rc)
+	if err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), ":")
+		if !(found) {
+			continue
+		}
+		if err := fn(key, strings.TrimSpace(value)); err != nil {
+			return fmt.Errorf("%w: %s: %s: %v", ErrParse, rc, key, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// readStatus reads the memory, context switches and CPU affinity of the
+// process.
+func (d *ProcDetails) readStatus(pid int) error {
+	return scanFields(pid, "status", func(key string, value string) (err error) {
+		switch key {
+		case "VmRSS":
+			d.VmRSS, err = strconv.ParseUint(strings.TrimSuffix(value, " kB"), 10, 64)
+			d.VmRSS *= 1024
+		case "voluntary_ctxt_switches":
+			d.VoluntaryCtxtSwitches, err = strconv.ParseUint(value, 10, 64)
+		case "nonvoluntary_ctxt_switches":
+			d.NonvoluntaryCtxtSwitches, err = strconv.ParseUint(value, 10, 64)
+		case "Cpus_allowed_list":
+			d.CpusAllowedList = value
+		}
+		return
+	})
+}
+
+// readIO reads the bytes that the process read from and wrote to storage.
+func (d *ProcDetails) readIO(pid int) error {
+	return scanFields(pid, "io", func(key string, value string) (err error) {
+		switch key {
+		case "read_bytes":
+			d.ReadBytes, err = strconv.ParseUint(value, 10, 64)
+		case "write_bytes":
+			d.WriteBytes, err = strconv.ParseUint(value, 10, 64)
+		}
+		return
+	})
+}
+
+// readSchedstat reads the time that the process spent on CPU and waiting on
+// the runqueue, and its number of timeslices.
+func (d *ProcDetails) readSchedstat(pid int) error {
+	data, err := GetResource(pid, "schedstat")
+	if err != nil {
+		return err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 3 {
+		return fmt.Errorf("%w: schedstat: %q", ErrParse, data)
+	}
+	for i, value := range []*uint64{&d.RunTime, &d.WaitTime, &d.Timeslices} {
+		if *value, err = strconv.ParseUint(fields[i], 10, 64); err != nil {
+			return fmt.Errorf("%w: schedstat: %v", ErrParse, err)
+		}
+	}
+	return nil
+}
+
+// columns returns the details, in raw and values formats order. The
+// arguments come last, since they may hold spaces.
+func (d *ProcDetails) columns() []interface{} {
+	return []interface{}{
+		d.Exe,
+		d.VmRSS,
+		d.VoluntaryCtxtSwitches,
+		d.NonvoluntaryCtxtSwitches,
+		d.CpusAllowedList,
+		d.ReadBytes,
+		d.WriteBytes,
+		d.RunTime,
+		d.WaitTime,
+		d.Timeslices,
+		strings.Join(d.Cmdline, " "),
+	}
+}
+
+// setDetails reads the details of the process. Unreadable files are skipped,
+// and the first error is returned.
+func (p *Proc) setDetails() (err error) {
+	d := new(ProcDetails)
+	for _, read := range []func(int) error{
+		d.readCmdline, d.readExe, d.readStatus, d.readIO, d.readSchedstat,
+	} {
+		if failure := read(p.Pid); failure != nil && err == nil {
+			err = failure
+		}
+	}
+	p.ProcDetails = d
+	return
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
@@ -0,0 +1,113 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// liveProcRoot is where the proc filesystem of the running system is mounted.
+const liveProcRoot = "/proc"
+
+// ProcFS is some proc filesystem mounted at Root, either the one of the
+// running system or some directory of recorded files, like
+// Root/PID/stat, Root/PID/cgroup and Root/uptime.
+type ProcFS struct {
+	Root string
+}
+
+// procFS is the proc filesystem that nicy reads, found under the fsroot
+// directory.
+var procFS = NewProcFS("/")
+
+// NewProcFS returns the proc filesystem found under root directory.
+func NewProcFS(root string) ProcFS {
+	return ProcFS{Root: filepath.Join(root, "proc")}
+}
+
+// Live returns whether the proc filesystem is the one of the running system.
+// Otherwise, the processes can not be adjusted, and what proc files do not
+// tell is left to default values.
+func (fs ProcFS) Live() bool {
+	return filepath.Clean(fs.Root) == liveProcRoot
+}
+
+// Path returns the path of some file, relative to the root.
+func (fs ProcFS) Path(elem ...string) string {
+	return filepath.Join(append([]string{fs.Root}, elem...)...)
+}
+
+// Resource reads the file rc of the process.
+func (fs ProcFS) Resource(pid int, rc string) ([]byte, error) {
+	return os.ReadFile(fs.Path(strconv.Itoa(pid), rc))
+}
+
+// StatFiles returns the paths of the stat files of all the processes.
+func (fs ProcFS) StatFiles() ([]string, error) {
+	return filepath.Glob(fs.Path("[0-9]*", "stat"))
+}
+
+// TaskStatFiles returns the paths of the stat files of all the threads of
+// the process.
+func (fs ProcFS) TaskStatFiles(pid int) ([]string, error) {
+	return filepath.Glob(fs.Path(strconv.Itoa(pid), "task", "[0-9]*", "stat"))
+}
+
+// Uid returns the effective user id of the process, -1 when unknown. Unless
+// live, the owner of the recorded files does not matter and the status file
+// is read instead.
+func (fs ProcFS) Uid(pid int) int {
+	if fs.Live() {
+		if stat, err := GetStat(fs.Path(strconv.Itoa(pid))); err == nil {
+			return int(stat.Uid)
+		}
+		return -1
+	}
+	data, err := fs.Resource(pid, "status")
+	if err != nil {
+		return -1
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		// Uid:	real	effective	saved	filesystem
+		if fields := strings.Fields(scanner.Text()); len(fields) > 2 && fields[0] == "Uid:" {
+			if uid, err := strconv.Atoi(fields[2]); err == nil {
+				return uid
+			}
+		}
+	}
+	return -1
+}
+
+// checkLiveProcFS returns an error, unless the proc filesystem is live or
+// dry-run is set.
+func checkLiveProcFS() error {
+	if procFS.Live() || viper.GetBool("dry-run") {
+		return nil
+	}
+	return fmt.Errorf("%w: %s: not live, dry-run required", ErrInvalid, procFS.Root)
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
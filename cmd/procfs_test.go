@@ -0,0 +1,196 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// useTestdata reads the processes recorded under testdata directory, until
+// the test ends.
+func useTestdata(t *testing.T) {
+	t.Helper()
+	saved := procFS
+	procFS = NewProcFS("testdata")
+	t.Cleanup(func() { procFS = saved })
+}
+
+// testPresetCache returns the presets found in testdata/presets.yaml file.
+func testPresetCache(t *testing.T) *PresetCache {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "presets.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, confName+"."+confType), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	pc := NewPresetCache()
+	if err := pc.LoadFromDirs([]string{dir}); err != nil {
+		t.Fatal(err)
+	}
+	if err := pc.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	return &pc
+}
+
+func TestFilteredProcs(t *testing.T) {
+	useTestdata(t)
+	tests := []struct {
+		scope string
+		want  []int
+	}{
+		{"all", []int{4242, 4243, 4244, 4246}},
+		{"global", []int{4242, 4243, 4244, 4246}},
+		{"system", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scope, func(t *testing.T) {
+			var got []int
+			for _, p := range FilteredProcs(GetFilterer(tt.scope)) {
+				got = append(got, p.Pid)
+			}
+			if !(reflect.DeepEqual(got, tt.want)) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilteredProcsFields(t *testing.T) {
+	useTestdata(t)
+	procs := FilteredProcs(pgrpFilter(4243))
+	if len(procs) != 2 {
+		t.Fatalf("got %d processes, want 2", len(procs))
+	}
+	p := procs[1]
+	tests := []struct {
+		name      string
+		got, want any
+	}{
+		{"pid", p.Pid, 4244},
+		{"comm", p.Comm, "Web Content) (x)"},
+		{"ppid", p.Ppid, 4243},
+		{"nice", p.Nice, 5},
+		{"uid", p.Uid, 1000},
+		{"slice", p.Slice, "user.slice"},
+		{"unit", p.Unit, "app-firefox.scope"},
+		{"oom_score_adj", p.OomScoreAdj, 100},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+// reviewTestdata returns the group jobs for the processes recorded under
+// testdata directory, by process group.
+func reviewTestdata(t *testing.T, pc *PresetCache) map[int]*ProcGroupJob {
+	t.Helper()
+	output := make(chan *ProcGroupJob, 8)
+	go func() {
+		pc.SendGroupJobs(FilteredProcs(GetFilterer("all")), output)
+		close(output)
+	}()
+	jobs := make(map[int]*ProcGroupJob)
+	for job := range output {
+		jobs[job.Pgrp] = job
+	}
+	return jobs
+}
+
+func TestReviewTestdata(t *testing.T) {
+	useTestdata(t)
+	pc := testPresetCache(t)
+	type want struct {
+		pids []int
+		diff BaseProfile
+	}
+	tests := []struct {
+		name  string
+		group bool
+		jobs  map[int]want
+	}{
+		{"rules", false, map[int]want{
+			4242: {[]int{4242}, BaseProfile{Nice: -11, IOClass: "realtime"}},
+			4243: {[]int{4243}, BaseProfile{Nice: -3, OomScoreAdj: 1000}},
+		}},
+		{"group", true, map[int]want{
+			4242: {[]int{4242}, BaseProfile{Nice: -11, IOClass: "realtime"}},
+			4243: {[]int{4243, 4244}, BaseProfile{Nice: -3, OomScoreAdj: 1000}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("group", tt.group)
+			defer viper.Set("group", false)
+			jobs := reviewTestdata(t, pc)
+			if len(jobs) != len(tt.jobs) {
+				t.Errorf("got %d jobs, want %d", len(jobs), len(tt.jobs))
+			}
+			for pgrp, want := range tt.jobs {
+				job, found := jobs[pgrp]
+				if !(found) {
+					t.Errorf("%d: no job", pgrp)
+					continue
+				}
+				if !(reflect.DeepEqual(job.Pids, want.pids)) {
+					t.Errorf("%d: pids: got %v, want %v", pgrp, job.Pids, want.pids)
+				}
+				if job.Diff.BaseProfile != want.diff {
+					t.Errorf("%d: diff: got %+v, want %+v", pgrp, job.Diff.BaseProfile, want.diff)
+				}
+			}
+		})
+	}
+}
+
+func TestReviewTestdataLoad(t *testing.T) {
+	useTestdata(t)
+	pc := testPresetCache(t)
+	procs := FilteredProcs(GetScopeOnlyFilterer("all"))
+	for _, p := range procs {
+		if p.OomScoreAdj != 0 {
+			t.Errorf("%d: oom_score_adj %d read before review", p.Pid, p.OomScoreAdj)
+		}
+	}
+	output := make(chan *ProcGroupJob, 8)
+	go func() {
+		pc.SendGroupJobs(procs, output)
+		close(output)
+	}()
+	for range output {
+	}
+	// 4243 gets some job, unlike 4244 without group option
+	for _, tt := range []struct{ pid, want int }{{4243, 100}, {4244, 0}} {
+		for _, p := range procs {
+			if p.Pid == tt.pid && p.OomScoreAdj != tt.want {
+				t.Errorf("%d: got oom_score_adj %d, want %d", p.Pid, p.OomScoreAdj, tt.want)
+			}
+		}
+	}
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
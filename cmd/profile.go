@@ -30,6 +30,7 @@ type Profile struct {
 	// and eventually adjust scope properties in BaseCgroup
 	BaseCgroup  `yaml:"basecgroup,omitempty,flow"`
 	BaseProfile `yaml:"baseprofile,omitempty,flow"`
+	Variants    `yaml:"variants,omitempty"`
 	CgroupKey   string `yaml:"cgroup,omitempty" json:"cgroup,omitempty"`
 	ProfileKey  string `yaml:"profile,omitempty" json:"profile,omitempty"`
 	Origin      string `yaml:"origin,omitempty" json:"origin,omitempty"`
@@ -54,3 +55,31 @@ func (p *Profile) ToRule() Rule {
 		},
 	}
 }
+
+// Unprivileged resets the attributes that require privileges, unless set to
+// the same values in granted, and returns their names. Without privileges,
+// the nice value can only be raised and the OOM score adjustment too, while
+// the realtime scheduling policies and I/O class are not allowed.
+func (p *BaseProfile) Unprivileged(granted BaseProfile) (rejected []string) {
+	if p.Nice < 0 && p.Nice != granted.Nice {
+		p.Nice = 0
+		rejected = append(rejected, "nice")
+	}
+	if (p.Sched == "fifo" || p.Sched == "rr") && p.Sched != granted.Sched {
+		p.Sched, p.RTPrio = "", 0
+		rejected = append(rejected, "sched")
+	}
+	if p.RTPrio != 0 && p.RTPrio != granted.RTPrio {
+		p.RTPrio = 0
+		rejected = append(rejected, "rtprio")
+	}
+	if p.IOClass == "realtime" && p.IOClass != granted.IOClass {
+		p.IOClass, p.IONice = "", 0
+		rejected = append(rejected, "ioclass")
+	}
+	if p.OomScoreAdj < 0 && p.OomScoreAdj != granted.OomScoreAdj {
+		p.OomScoreAdj = 0
+		rejected = append(rejected, "oom_score_adj")
+	}
+	return
+}
@@ -0,0 +1,224 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Reclaim default values
+const (
+	defaultReclaimIdle = 5 * time.Minute
+	defaultReclaimCPU  = 1.0
+)
+
+// Reclaim is the memory pushed out of the nicy slice of some cgroup, once its
+// processes used less than CPU percent of one CPU during Idle. Amount is
+// either some size, like 256M, or some percentage of the memory used by the
+// slice, like 25%.
+type Reclaim struct {
+	Idle   time.Duration `yaml:"idle,omitempty" json:"idle,omitempty"`
+	CPU    float64       `yaml:"cpu,omitempty" json:"cpu,omitempty"`
+	Amount string        `yaml:"amount" json:"amount"`
+}
+
+// Validate checks the reclaim policy and sets default values.
+func (r *Reclaim) Validate() error {
+	if _, err := r.Bytes(1); err != nil {
+		return err
+	}
+	switch {
+	case r.Idle == 0:
+		r.Idle = defaultReclaimIdle
+	case r.Idle < time.Minute:
+		return fmt.Errorf("%w: reclaim: idle: %v", ErrInvalid, r.Idle)
+	}
+	switch {
+	case r.CPU == 0:
+		r.CPU = defaultReclaimCPU
+	case r.CPU < 0 || r.CPU > 100:
+		return fmt.Errorf("%w: reclaim: cpu: %v", ErrInvalid, r.CPU)
+	}
+	return nil
+}
+
+// Bytes returns the amount of memory to reclaim, when current bytes are used.
+func (r *Reclaim) Bytes(current uint64) (uint64, error) {
+	if strings.HasSuffix(r.Amount, "%") {
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(r.Amount, "%"), 64)
+		if err != nil || percent <= 0 || percent > 100 {
+			return 0, fmt.Errorf("%w: reclaim: amount: %q", ErrInvalid, r.Amount)
+		}
+		return uint64(float64(current) * percent / 100), nil
+	}
+	size, err := parseBytes(r.Amount)
+	if err != nil || size == 0 {
+		return 0, fmt.Errorf("%w: reclaim: amount: %q", ErrInvalid, r.Amount)
+	}
+	return size, nil
+}
+
+// byteUnits are the suffixes of sizes, with base 1024.
+const byteUnits = "KMGT"
+
+// parseBytes parses some size, like 512K, 256M or 2G.
+func parseBytes(s string) (uint64, error) {
+	var (
+		shift  uint
+		digits = s
+	)
+	if n := len(s); n > 0 {
+		if i := strings.IndexByte(byteUnits, strings.ToUpper(s)[n-1]); i >= 0 {
+			shift, digits = uint(10*(i+1)), s[:n-1]
+		}
+	}
+	value, err := strconv.ParseUint(digits, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if value > math.MaxUint64>>shift {
+		return 0, fmt.Errorf("%w: size: %q", ErrInvalid, s)
+	}
+	return value << shift, nil
+}
+
+// formatBytes formats some size, like 12.5M.
+func formatBytes(size uint64) string {
+	value, unit := float64(size), ""
+	for i := 0; value >= 1024 && i < len(byteUnits); i++ {
+		value, unit = value/1024, byteUnits[i:i+1]
+	}
+	if unit == "" {
+		return fmt.Sprintf("%dB", size)
+	}
+	return fmt.Sprintf("%.1f%s", value, unit)
+}
+
+// reclaimState remembers since when some nicy slice is idle.
+type reclaimState struct {
+	usage uint64    // usage_usec last read
+	last  time.Time // when read
+	since time.Time // idle since
+}
+
+// Reclaimer pushes out the memory of the idle nicy slices of the cgroups
+// with some reclaim policy.
+type Reclaimer struct {
+	mu     sync.Mutex
+	states map[string]*reclaimState // by slice path
+}
+
+// NewReclaimer returns some reclaimer.
+func NewReclaimer() *Reclaimer {
+	return &Reclaimer{states: make(map[string]*reclaimState)}
+}
+
+// Update reads the CPU time used inside each nicy slice with reclaim policy,
+// then reclaims the memory of the slices idle for long enough. The slices
+// must then stay idle as long again before next reclaim.
+func (r *Reclaimer) Update(pc *PresetCache, now time.Time) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seen := make(map[string]bool)
+	for key := range pc.Cgroups {
+		cgroup, _ := pc.Cgroup(key)
+		policy := cgroup.Reclaim
+		if policy == nil {
+			continue
+		}
+		for _, path := range nicySliceCgroups(key) {
+			usage, err := CgroupCPUUsage(path)
+			if err != nil { // slice not running
+				continue
+			}
+			seen[path] = true
+			state, found := r.states[path]
+			if !(found) || usage < state.usage {
+				r.states[path] = &reclaimState{usage: usage, last: now, since: now}
+				continue
+			}
+			elapsed := now.Sub(state.last)
+			if elapsed <= 0 {
+				continue
+			}
+			share := 100 * float64(usage-state.usage) / float64(elapsed.Microseconds())
+			state.usage, state.last = usage, now
+			if share > policy.CPU {
+				state.since = now
+				continue
+			}
+			if now.Sub(state.since) < policy.Idle {
+				continue
+			}
+			state.since = now
+			nonfatal(reclaim(key, path, policy))
+		}
+	}
+	for path := range r.states {
+		if !(seen[path]) {
+			delete(r.states, path)
+		}
+	}
+}
+
+// reclaim reclaims the memory of the nicy slice for cgroup key, at path,
+// then logs the amount reclaimed.
+func reclaim(key string, path string, policy *Reclaim) error {
+	before, err := CgroupMemoryCurrent(path)
+	if err != nil {
+		return fmt.Errorf("%w: nicy-%s.slice: %v", ErrFailure, key, err)
+	}
+	amount, err := policy.Bytes(before)
+	if err != nil || amount == 0 {
+		return err
+	}
+	if viper.GetBool("dry-run") {
+		inform(key, fmt.Sprintf(
+			"nicy-%s.slice: idle, reclaiming %s of %s", key, formatBytes(amount), formatBytes(before),
+		))
+		return nil
+	}
+	switch err := ReclaimCgroup(path, amount); {
+	case errors.Is(err, syscall.EAGAIN): // partly reclaimed
+		debug(err)
+	case err != nil:
+		return fmt.Errorf("%w: nicy-%s.slice: %v", ErrFailure, key, err)
+	}
+	after, err := CgroupMemoryCurrent(path)
+	if err != nil || after > before {
+		after = before
+	}
+	inform(key, fmt.Sprintf(
+		"nicy-%s.slice: idle, reclaimed %s of %s requested, %s left",
+		key, formatBytes(before-after), formatBytes(amount), formatBytes(after),
+	))
+	return nil
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
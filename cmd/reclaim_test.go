@@ -0,0 +1,98 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseBytes(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    uint64
+		wantErr bool
+	}{
+		{"0", 0, false},
+		{"4096", 4096, false},
+		{"512K", 512 << 10, false},
+		{"512k", 512 << 10, false},
+		{"256M", 256 << 20, false},
+		{"2G", 2 << 30, false},
+		{"1T", 1 << 40, false},
+		{"16777215T", 16777215 << 40, false},
+		{"16777216T", 0, true}, // overflow
+		{"", 0, true},
+		{"M", 0, true},
+		{"1.5G", 0, true},
+		{"-1M", 0, true},
+		{"1P", 0, true},
+		{"1 G", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := parseBytes(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReclaimBytes(t *testing.T) {
+	tests := []struct {
+		amount  string
+		current uint64
+		want    uint64
+		wantErr bool
+	}{
+		{"256M", 1 << 30, 256 << 20, false},
+		{"256M", 0, 256 << 20, false},
+		{"25%", 1 << 30, 256 << 20, false},
+		{"100%", 1000, 1000, false},
+		{"12.5%", 800, 100, false},
+		{"25%", 0, 0, false},
+		{"0%", 1000, 0, true},
+		{"101%", 1000, 0, true},
+		{"-5%", 1000, 0, true},
+		{"x%", 1000, 0, true},
+		{"0", 1000, 0, true},
+		{"0M", 1000, 0, true},
+		{"", 1000, 0, true},
+		{"lots", 1000, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.amount, func(t *testing.T) {
+			r := Reclaim{Amount: tt.amount}
+			got, err := r.Bytes(tt.current)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !(errors.Is(err, ErrInvalid)) {
+				t.Errorf("got %v, want ErrInvalid", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
@@ -98,6 +98,7 @@ func init() {
 	addVerboseFlag()
 	addCacheFlag()
 
+	fs.String("fsroot", "/", "read proc and sys filesystems under `dir`")
 	fs.StringSlice("confdirs", []string{}, "user and system presets directories")
 	fs.BoolP("debug", "D", false, "show debug output")
 	fs.MarkHidden("confdirs")
@@ -123,6 +124,12 @@ func init() {
 	rootCmd.AddCommand(dumpCmd)
 	rootCmd.AddCommand(setCmd)
 	rootCmd.AddCommand(controlCmd)
+	rootCmd.AddCommand(ctlCmd)
+	rootCmd.AddCommand(pinCmd)
+	rootCmd.AddCommand(unpinCmd)
+	rootCmd.AddCommand(boostCmd)
+	rootCmd.AddCommand(freezeCmd)
+	rootCmd.AddCommand(thawCmd)
 	rootCmd.AddCommand(installCmd)
 }
 
@@ -242,6 +249,7 @@ func initConfig() {
 	// Update values
 	viper.Set("scripts.location", expandPath(viper.GetString("scripts.location")))
 	viper.Set("uid", uid)
+	procFS = NewProcFS(fsRoot("fsroot"))
 	// Debug
 	// Display the capabilities of the running process
 	debug(getCapabilities())
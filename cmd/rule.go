@@ -27,12 +27,18 @@ type BaseRule struct {
 	Env             map[string]string `yaml:"env,omitempty,flow" json:"env,omitempty"`
 	SliceProperties []string          `yaml:"slice_properties,omitempty,flow" json:"slice_properties,omitempty"`
 	Credentials     []string          `yaml:"cred,omitempty,flow" json:"cred,omitempty"`
+	// Inherit: apply to descendant processes, up to `Depth` generations
+	Inherit bool `yaml:"inherit,omitempty" json:"inherit,omitempty"`
+	Depth   int  `yaml:"depth,omitempty" json:"depth,omitempty"`
+	// Threads: override the profile for the threads matching some name
+	Threads ThreadProfiles `yaml:"threads,omitempty" json:"threads,omitempty"`
 }
 
 type Rule struct {
 	BaseProfile `yaml:"baseprofile,omitempty,flow"`
 	BaseCgroup  `yaml:"basecgroup,omitempty,flow"`
 	BaseRule    `yaml:"baserule,omitempty,flow"`
+	Variants    `yaml:"variants,omitempty"`
 	RuleKey     string `yaml:"name,omitempty" json:"name,omitempty"`
 	Origin      string `yaml:"origin,omitempty" json:"origin,omitempty"`
 }
@@ -101,6 +107,7 @@ func (r *Rule) HasMemoryMax() bool {
 func (r *Rule) CgroupOnly() {
 	r.ProfileKey = ""
 	r.BaseProfile = BaseProfile{}
+	r.Threads = nil
 }
 
 func (r *Rule) SetCgroup(cgroup string) {
@@ -124,6 +131,29 @@ func (r *Rule) GetCredentials() (result []string) {
 	if r.IOClass == "realtime" {
 		result = append(result, "ioclass")
 	}
+	for _, value := range r.Threads.Credentials() {
+		if !(Contains(result, value)) {
+			result = append(result, value)
+		}
+	}
+	return
+}
+
+// Unprivileged resets the attributes of the rule and of its thread profiles
+// that require privileges, unless granted, and returns their names.
+func (r *Rule) Unprivileged(granted Rule) (rejected []string) {
+	rejected = r.BaseProfile.Unprivileged(granted.BaseProfile)
+	if len(r.Threads) == 0 {
+		return
+	}
+	threads := make(ThreadProfiles, len(r.Threads)) // shared with the cache
+	for pattern, profile := range r.Threads {
+		for _, name := range profile.Unprivileged(granted.Threads[pattern]) {
+			rejected = append(rejected, "threads."+pattern+"."+name)
+		}
+		threads[pattern] = profile
+	}
+	r.Threads = threads
 	return
 }
 
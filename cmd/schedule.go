@@ -0,0 +1,154 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// weekdays maps the day names to their number, from sunday.
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// minutesPerDay is the end of the day, in minutes.
+const minutesPerDay = 24 * 60
+
+// Window is some time range, on some days of the week, with the variant that
+// applies while open. Days can be listed one by one, like mon, or as range,
+// like mon-fri. Times use the 15:04 layout. A window ending before it starts
+// ends on the next day.
+type Window struct {
+	Days    []string `yaml:"days,omitempty,flow" json:"days,omitempty"`
+	From    string   `yaml:"from,omitempty" json:"from,omitempty"`
+	To      string   `yaml:"to,omitempty" json:"to,omitempty"`
+	Variant `yaml:",inline"`
+}
+
+// parseMinutes returns the time of the day, in minutes, or value when empty.
+func parseMinutes(s string, value int) (int, error) {
+	if s == "" {
+		return value, nil
+	}
+	if s == "24:00" {
+		return minutesPerDay, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: time: %q", ErrInvalid, s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// weekdaySet returns the days of the window, all days when none.
+func (w *Window) weekdaySet() (result [7]bool, err error) {
+	if len(w.Days) == 0 {
+		for i := range result {
+			result[i] = true
+		}
+		return
+	}
+	for _, item := range w.Days {
+		first, last, found := strings.Cut(strings.ToLower(item), "-")
+		if !(found) {
+			last = first
+		}
+		start, ok1 := weekdays[first]
+		end, ok2 := weekdays[last]
+		if !(ok1 && ok2) {
+			return result, fmt.Errorf("%w: days: %q", ErrInvalid, item)
+		}
+		for day := start; ; day = (day + 1) % 7 {
+			result[day] = true
+			if day == end {
+				break
+			}
+		}
+	}
+	return
+}
+
+// bounds returns the days, start and end of the window.
+func (w *Window) bounds() (days [7]bool, from int, to int, err error) {
+	if days, err = w.weekdaySet(); err != nil {
+		return
+	}
+	if from, err = parseMinutes(w.From, 0); err != nil {
+		return
+	}
+	to, err = parseMinutes(w.To, minutesPerDay)
+	return
+}
+
+// Validate checks the days and times of the window.
+func (w *Window) Validate() error {
+	if _, from, to, err := w.bounds(); err != nil {
+		return fmt.Errorf("schedule: %w", err)
+	} else if from == to {
+		return fmt.Errorf("%w: schedule: empty window %s-%s", ErrInvalid, w.From, w.To)
+	}
+	return nil
+}
+
+// Open returns whether the window is open at time t.
+func (w *Window) Open(t time.Time) bool {
+	days, from, to, err := w.bounds()
+	if err != nil {
+		return false
+	}
+	day, minutes := t.Weekday(), t.Hour()*60+t.Minute()
+	if from < to {
+		return days[day] && from <= minutes && minutes < to
+	}
+	// ending on the next day
+	return (days[day] && minutes >= from) || (days[(day+6)%7] && minutes < to)
+}
+
+// OpenWindows returns the schedule windows of the presets that are open at
+// time now, one per line, in order.
+func (pc *PresetCache) OpenWindows(now time.Time) string {
+	var result []string
+	add := func(kind string, key string, v Variants) {
+		for i := range v.Schedule {
+			if v.Schedule[i].Open(now) {
+				result = append(result, fmt.Sprintf("%s %s %d", kind, key, i))
+			}
+		}
+	}
+	for key, cgroups := range pc.Cgroups {
+		add("cgroup", key, ActivePreset(cgroups).Variants)
+	}
+	for key, profiles := range pc.Profiles {
+		add("profile", key, ActivePreset(profiles).Variants)
+	}
+	for key, rules := range pc.Rules {
+		add("rule", key, ActivePreset(rules).Variants)
+	}
+	sort.Strings(result)
+	return strings.Join(result, "\n")
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
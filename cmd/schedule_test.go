@@ -0,0 +1,65 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+// weekdayAt returns the time on some day of the week of 2026-10-12, from monday, at
+// hh:mm.
+func weekdayAt(day time.Weekday, hh, mm int) time.Time {
+	offset := (int(day) + 6) % 7 // days since monday
+	return time.Date(2026, time.October, 12+offset, hh, mm, 0, 0, time.UTC)
+}
+
+func TestWindowOpen(t *testing.T) {
+	tests := []struct {
+		name   string
+		window Window
+		t      time.Time
+		want   bool
+	}{
+		{"day inside", Window{Days: []string{"mon-fri"}, From: "09:00", To: "17:00"}, weekdayAt(time.Friday, 10, 0), true},
+		{"day start", Window{Days: []string{"mon-fri"}, From: "09:00", To: "17:00"}, weekdayAt(time.Friday, 9, 0), true},
+		{"day end", Window{Days: []string{"mon-fri"}, From: "09:00", To: "17:00"}, weekdayAt(time.Friday, 17, 0), false},
+		{"day other day", Window{Days: []string{"mon-fri"}, From: "09:00", To: "17:00"}, weekdayAt(time.Saturday, 10, 0), false},
+		{"overnight evening", Window{Days: []string{"fri"}, From: "22:00", To: "06:00"}, weekdayAt(time.Friday, 23, 0), true},
+		{"overnight next morning", Window{Days: []string{"fri"}, From: "22:00", To: "06:00"}, weekdayAt(time.Saturday, 5, 59), true},
+		{"overnight end", Window{Days: []string{"fri"}, From: "22:00", To: "06:00"}, weekdayAt(time.Saturday, 6, 0), false},
+		{"overnight same morning", Window{Days: []string{"fri"}, From: "22:00", To: "06:00"}, weekdayAt(time.Friday, 5, 0), false},
+		{"overnight next evening", Window{Days: []string{"fri"}, From: "22:00", To: "06:00"}, weekdayAt(time.Saturday, 23, 0), false},
+		{"overnight across week", Window{Days: []string{"sun"}, From: "22:00", To: "06:00"}, weekdayAt(time.Monday, 1, 0), true},
+		{"overnight day range", Window{Days: []string{"fri-mon"}, From: "23:00", To: "04:00"}, weekdayAt(time.Tuesday, 3, 0), true},
+		{"overnight after range", Window{Days: []string{"fri-mon"}, From: "23:00", To: "04:00"}, weekdayAt(time.Tuesday, 23, 30), false},
+		{"overnight every day", Window{From: "23:00", To: "01:00"}, weekdayAt(time.Wednesday, 0, 30), true},
+		{"until midnight", Window{Days: []string{"fri"}, From: "20:00", To: "24:00"}, weekdayAt(time.Friday, 23, 59), true},
+		{"after midnight", Window{Days: []string{"fri"}, From: "20:00", To: "24:00"}, weekdayAt(time.Saturday, 0, 0), false},
+		{"invalid time", Window{From: "25:00", To: "06:00"}, weekdayAt(time.Friday, 1, 0), false},
+		{"invalid day", Window{Days: []string{"friday"}}, weekdayAt(time.Friday, 1, 0), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.window.Open(tt.t); got != tt.want {
+				t.Errorf("%s: got %v, want %v", tt.t.Format("Mon 15:04"), got, tt.want)
+			}
+		})
+	}
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
@@ -31,12 +31,17 @@ var setCmd = &cobra.Command{
 
 The processes are selected when their group leader matches an existing rule.
 The --user option is the implied default, when none is given.
-Only superuser can run set command with --system, --global or --all option.`,
+Only superuser can run set command with --system, --global or --all option.
+With --global or --all option, processes inside user slices are managed with
+the presets of their owner.`,
 	Args:                  cobra.MaximumNArgs(0),
 	DisableFlagsInUseLine: true,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
 		// Bind shared flags
-		return viper.BindPFlags(cmd.LocalNonPersistentFlags())
+		if err := viper.BindPFlags(cmd.LocalNonPersistentFlags()); err != nil {
+			return err
+		}
+		return checkLiveProcFS()
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		viper.Set("tag", "set")
@@ -67,6 +72,7 @@ func init() {
 	fs.SetInterspersed(false)
 	viper.Set("scopes", addScopeFlags(setCmd))
 	addDryRunFlag(setCmd)
+	addMatchFlags(setCmd)
 	// addVerboseFlag(setCmd)
 	setCmd.InheritedFlags().SortFlags = false
 }
@@ -82,15 +88,14 @@ func doSetCmd(tag string, filter ProcFilterer, std *Streams) (err error) {
 		go func() {
 			defer wg.Done()
 			for job := range jobs {
-				err = job.Run("", std)
-				if err != nil {
-					return
+				if err := job.Run("", std); err != nil {
+					warn(err)
 				}
 			}
 		}()
 	}
 	wg.Add(1) // get jobs
-	go presetCache.GenerateGroupJobs(procs, jobs, &wg)
+	go NewUserCaches(&presetCache, perUserScope(filter)).GenerateGroupJobs(procs, jobs, &wg)
 	// send input
 	// filter := GetScopeOnlyFilterer(scope)
 	if viper.GetBool("dry-run") || viper.GetBool("verbose") {
@@ -0,0 +1,222 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Control socket protocol: one JSON request per line, answered by one JSON
+// response per line.
+
+// controlScopes are the scopes that some control command can run with.
+var controlScopes = []string{"user", "global", "system", "all"}
+
+// controlSocket returns the path of the socket of the control command running
+// with scope, unless the socket setting gives some path.
+func controlSocket(scope string) string {
+	if path := viper.GetString("socket"); len(path) > 0 {
+		return path
+	}
+	return filepath.Join(viper.GetString("runtimedir"), "control-"+scope+".sock")
+}
+
+// ctlTimeout is the time allowed to a client for each request.
+const ctlTimeout = 30 * time.Second
+
+type CtlRequest struct {
+	Command string `json:"command"`
+	Pid     int    `json:"pid,omitempty"`
+	Preset  string `json:"preset,omitempty"`
+}
+
+type CtlResponse struct {
+	Ok    bool            `json:"ok"`
+	Error string          `json:"error,omitempty"`
+	Data  json.RawMessage `json:"data,omitempty"`
+}
+
+// CtlHandler answers a request, returning data to be marshalled.
+type CtlHandler func(req CtlRequest) (any, error)
+
+// CtlServer listens on the control socket.
+type CtlServer struct {
+	path     string
+	listener net.Listener
+	handlers map[string]CtlHandler
+}
+
+// removeStaleSocket removes the unix socket at path, if any, unless some
+// other process is listening on it. Anything else than a socket is left.
+func removeStaleSocket(name, path string) error {
+	info, err := os.Lstat(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil
+	case err != nil:
+		return fmt.Errorf("%w: %s: %v", ErrFailure, name, err)
+	case info.Mode()&fs.ModeSocket == 0:
+		return fmt.Errorf("%w: %s: %s: not a socket", ErrInvalid, name, path)
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return fmt.Errorf("%w: %s", ErrAlready, path)
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrFailure, name, err)
+	}
+	return nil
+}
+
+// NewCtlServer listens on the unix socket at path. A stale socket is removed,
+// but a socket that some other process is listening on is an error.
+func NewCtlServer(path string) (*CtlServer, error) {
+	if err := removeStaleSocket("control socket", path); err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: control socket: %v", ErrFailure, err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("%w: control socket: %v", ErrFailure, err)
+	}
+	return &CtlServer{
+		path:     path,
+		listener: listener,
+		handlers: make(map[string]CtlHandler),
+	}, nil
+}
+
+// Handle registers the handler for command. Handlers must be registered
+// before serving.
+func (s *CtlServer) Handle(command string, h CtlHandler) {
+	s.handlers[command] = h
+}
+
+// Commands returns the registered commands.
+func (s *CtlServer) Commands() (result []string) {
+	for command := range s.handlers {
+		result = append(result, command)
+	}
+	sort.Strings(result)
+	return
+}
+
+// Serve accepts connections until the context is done.
+func (s *CtlServer) Serve(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		s.listener.Close()
+	}()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if !(errors.Is(err, net.ErrClosed)) {
+				nonfatal(fmt.Errorf("%w: control socket: %v", ErrFailure, err))
+			}
+			return
+		}
+		go s.serveConn(conn)
+	}
+}
+
+func (s *CtlServer) serveConn(conn net.Conn) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	encoder := json.NewEncoder(conn)
+	for {
+		conn.SetDeadline(time.Now().Add(ctlTimeout))
+		if !(scanner.Scan()) {
+			return
+		}
+		if err := encoder.Encode(s.answer(scanner.Bytes())); err != nil {
+			return
+		}
+	}
+}
+
+func (s *CtlServer) answer(line []byte) (resp CtlResponse) {
+	var req CtlRequest
+	if err := json.Unmarshal(line, &req); err != nil {
+		resp.Error = fmt.Errorf("%w: %v", ErrParse, err).Error()
+		return
+	}
+	h, found := s.handlers[req.Command]
+	if !(found) {
+		resp.Error = fmt.Errorf("%w: unknown command: %s", ErrInvalid, req.Command).Error()
+		return
+	}
+	data, err := h(req)
+	if err != nil {
+		resp.Error = err.Error()
+		return
+	}
+	if data != nil {
+		if resp.Data, err = json.Marshal(data); err != nil {
+			resp.Error = fmt.Errorf("%w: %v", ErrFailure, err).Error()
+			return
+		}
+	}
+	resp.Ok = true
+	return
+}
+
+// Close stops listening and removes the socket.
+func (s *CtlServer) Close() error {
+	err := s.listener.Close()
+	os.Remove(s.path)
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// SendCtlRequest sends the request to the control socket at path and returns
+// the response.
+func SendCtlRequest(path string, req CtlRequest) (resp CtlResponse, err error) {
+	conn, err := net.DialTimeout("unix", path, ctlTimeout)
+	if err != nil {
+		err = fmt.Errorf("%w: control socket: %v", ErrNotFound, err)
+		return
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(ctlTimeout))
+	if err = json.NewEncoder(conn).Encode(req); err != nil {
+		err = fmt.Errorf("%w: control socket: %v", ErrFailure, err)
+		return
+	}
+	if err = json.NewDecoder(conn).Decode(&resp); err != nil {
+		err = fmt.Errorf("%w: control socket: %v", ErrFailure, err)
+	}
+	return
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
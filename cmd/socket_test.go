@@ -0,0 +1,160 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// startCtlServer serves the echo and fail commands on some socket, until the
+// test ends, and returns the path of the socket.
+func startCtlServer(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ctl.sock")
+	s, err := NewCtlServer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Handle("echo", func(req CtlRequest) (any, error) {
+		return req, nil
+	})
+	s.Handle("fail", func(req CtlRequest) (any, error) {
+		return nil, ErrNotFound
+	})
+	s.Handle("none", func(req CtlRequest) (any, error) {
+		return nil, nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Serve(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+		s.Close()
+	})
+	return path
+}
+
+func TestCtlServer(t *testing.T) {
+	path := startCtlServer(t)
+	tests := []struct {
+		name     string
+		req      CtlRequest
+		wantOk   bool
+		wantErr  string
+		wantData any
+	}{
+		{"data", CtlRequest{Command: "echo", Pid: 42, Preset: "auto"}, true, "", CtlRequest{Command: "echo", Pid: 42, Preset: "auto"}},
+		{"no data", CtlRequest{Command: "none"}, true, "", nil},
+		{"handler error", CtlRequest{Command: "fail"}, false, ErrNotFound.Error(), nil},
+		{"unknown command", CtlRequest{Command: "reboot"}, false, ErrInvalid.Error() + ": unknown command: reboot", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := SendCtlRequest(path, tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Ok != tt.wantOk || resp.Error != tt.wantErr {
+				t.Errorf("got (%v, %q), want (%v, %q)", resp.Ok, resp.Error, tt.wantOk, tt.wantErr)
+			}
+			if tt.wantData == nil {
+				if len(resp.Data) > 0 {
+					t.Errorf("got data %s, want none", resp.Data)
+				}
+				return
+			}
+			var got CtlRequest
+			if err := json.Unmarshal(resp.Data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.wantData) {
+				t.Errorf("got data %+v, want %+v", got, tt.wantData)
+			}
+		})
+	}
+}
+
+func TestCtlServerConnection(t *testing.T) {
+	path := startCtlServer(t)
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// one response per line, malformed ones included
+	lines := []string{`{"command":"none"}`, `{"command":`, `{"command":"echo","pid":7}`}
+	want := []CtlResponse{
+		{Ok: true},
+		{Error: ErrParse.Error()},
+		{Ok: true, Data: json.RawMessage(`{"command":"echo","pid":7}`)},
+	}
+	reader := bufio.NewReader(conn)
+	for i, line := range lines {
+		if _, err := conn.Write([]byte(line + "\n")); err != nil {
+			t.Fatal(err)
+		}
+		answer, err := reader.ReadBytes('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got CtlResponse
+		if err := json.Unmarshal(answer, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Ok != want[i].Ok || string(got.Data) != string(want[i].Data) ||
+			!(strings.HasPrefix(got.Error, want[i].Error)) {
+			t.Errorf("line %q: got %+v, want %+v", line, got, want[i])
+		}
+	}
+}
+
+func TestNewCtlServer(t *testing.T) {
+	path := startCtlServer(t)
+	if _, err := NewCtlServer(path); !errors.Is(err, ErrAlready) {
+		t.Errorf("got error %v with running server, want %v", err, ErrAlready)
+	}
+	// stale socket
+	stale := filepath.Join(t.TempDir(), "ctl.sock")
+	listener, err := net.Listen("unix", stale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	listener.Close()
+	s, err := NewCtlServer(stale)
+	if err != nil {
+		t.Fatalf("got error %v with stale socket", err)
+	}
+	s.Close()
+	if _, err := SendCtlRequest(stale, CtlRequest{Command: "status"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v after close, want %v", err, ErrNotFound)
+	}
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
@@ -0,0 +1,682 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Failure backoff
+const (
+	backoffBase = 30 * time.Second // delay after first failure
+	backoffMax  = time.Hour        // maximum delay between retries
+	maxFailures = 5                // give up after that many failures
+)
+
+// procState remembers the processes that control command already handled.
+// It is nil for any other command.
+var procState *ProcState
+
+// procEntry remembers a process. A reused pid does not match the entry
+// anymore, while a renamed process is only reviewed again, keeping what is
+// pinned until it exits.
+type procEntry struct {
+	StartTime uint64
+	Comm      string
+	Pgrp      int
+	Settled   bool
+	Failures  int       // consecutive failures
+	RetryAt   time.Time // not before, after failure
+	LastError string
+	Applied   BaseProfile // last applied to the process group
+	Pinned    Rule        // attributes changed by hand since
+	Manual    bool        // pinned with pin command
+	Fg        bool        // in the foreground when last reviewed
+	Boosted   bool        // boosted with foreground profile
+	Realtime  bool        // moved to some realtime class by its rule
+	Demoted   time.Time   // not promoted again before, once demoted
+	Threads   int         // when settled, if some thread has a profile
+	Matched   bool        // counted as matching some rule
+}
+
+// GaveUp returns whether the process failed too many times.
+func (e *procEntry) GaveUp() bool {
+	return e.Failures >= maxFailures
+}
+
+// Backoff returns whether the process must not be reviewed yet.
+func (e *procEntry) Backoff(now time.Time) bool {
+	return e.GaveUp() || now.Before(e.RetryAt)
+}
+
+// Match returns whether p is the remembered process, by pid and start time.
+func (e *procEntry) Match(p *Proc) bool {
+	return e.StartTime == p.StartTime
+}
+
+// GroupStatus describes a managed process group and what was last found
+// diverging from its rule.
+type GroupStatus struct {
+	Pgrp     int            `yaml:"pgrp" json:"pgrp"`
+	Comm     string         `yaml:"comm" json:"comm"`
+	Pids     []int          `yaml:"pids" json:"pids"`
+	Settled  bool           `yaml:"settled" json:"settled"`
+	Diff     map[string]any `yaml:"diff,omitempty" json:"diff,omitempty"`
+	Failures int            `yaml:"failures,omitempty" json:"failures,omitempty"`
+	RetryAt  *time.Time     `yaml:"retry_at,omitempty" json:"retry_at,omitempty"`
+	GaveUp   bool           `yaml:"gave_up,omitempty" json:"gave_up,omitempty"`
+	Error    string         `yaml:"error,omitempty" json:"error,omitempty"`
+	Pinned   []string       `yaml:"pinned,omitempty" json:"pinned,omitempty"`
+}
+
+type ProcState struct {
+	mu       sync.Mutex
+	entries  map[int]*procEntry   // per pid
+	groups   map[int]*GroupStatus // per pgrp
+	reported map[int]bool         // pids given up and yet reported
+	MinAge   time.Duration
+}
+
+func NewProcState(minAge time.Duration) *ProcState {
+	return &ProcState{
+		entries:  make(map[int]*procEntry),
+		groups:   make(map[int]*GroupStatus),
+		reported: make(map[int]bool),
+		MinAge:   minAge,
+	}
+}
+
+// group records the status of the process group.
+func (s *ProcState) group(job *ProcGroupJob, settled bool) {
+	status := &GroupStatus{
+		Pgrp:    job.Pgrp,
+		Pids:    Clone(job.Pids),
+		Settled: settled,
+	}
+	if job.leader != nil {
+		status.Comm = job.leader.Proc.Comm
+		if e, found := s.entries[job.leader.Proc.Pid]; found {
+			status.Pinned = Keys(ToInterface(e.Pinned.BaseProfile))
+		}
+	}
+	if !(settled) {
+		status.Diff = ToInterface(job.Diff)
+	}
+	if previous, found := s.groups[job.Pgrp]; found {
+		status.Failures = previous.Failures
+		status.RetryAt = previous.RetryAt
+		status.GaveUp = previous.GaveUp
+		status.Error = previous.Error
+	}
+	s.groups[job.Pgrp] = status
+}
+
+// entry returns the entry for the process, adding a new one when the process
+// is unknown.
+func (s *ProcState) entry(p *Proc) *procEntry {
+	if e, found := s.entries[p.Pid]; found && e.Match(p) {
+		e.Pgrp = p.Pgrp
+		if e.Comm != p.Comm { // executed or renamed, maybe another rule
+			e.Comm, e.Settled = p.Comm, false
+		}
+		return e
+	}
+	e := &procEntry{
+		StartTime: p.StartTime, Comm: p.Comm, Pgrp: p.Pgrp, Fg: p.Foreground(),
+	}
+	s.entries[p.Pid] = e
+	return e
+}
+
+// Select splits procs per process group and returns the processes to review
+// and the processes too young to be reviewed yet or waiting after some
+// failure. Process groups whose members have all been settled and processes
+// given up are skipped, unless they moved to or from the foreground of their
+// terminal, with foreground option.
+func (s *ProcState) Select(procs []*Proc) (ready []*Proc, deferred []*Proc) {
+	if s == nil {
+		return procs, nil
+	}
+	uptime, err := Uptime()
+	if err != nil {
+		uptime = 0
+	}
+	now := time.Now()
+	foreground := viper.GetString("foreground") != ""
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for group := range ProcByPgrp(procs).ByPgrp() {
+		var members []*Proc
+		settled := true
+		if s.manual(group) {
+			continue
+		}
+		for _, p := range group {
+			if s.MinAge > 0 && uptime > 0 && p.Age(uptime) < s.MinAge {
+				deferred = append(deferred, p)
+				continue
+			}
+			e := s.entry(p)
+			if e.GaveUp() {
+				continue
+			}
+			if e.Backoff(now) {
+				deferred = append(deferred, p)
+				continue
+			}
+			members = append(members, p)
+			if fg := p.Foreground(); foreground && fg != e.Fg {
+				e.Fg, e.Settled = fg, false
+			}
+			if e.Threads > 0 && e.Threads != p.NumThreads { // review new threads
+				e.Threads, e.Settled = 0, false
+			}
+			if !(e.Settled) {
+				settled = false
+			}
+		}
+		if len(members) > 0 && !(settled) {
+			ready = append(ready, members...)
+		}
+	}
+	sort.Sort(ProcByPid(ready))
+	return
+}
+
+// manual returns whether some process of the group is pinned with pin
+// command.
+func (s *ProcState) manual(group []*Proc) bool {
+	for _, p := range group {
+		if e, found := s.entries[p.Pid]; found && e.Match(p) && e.Manual {
+			return true
+		}
+	}
+	return false
+}
+
+// Wait returns the time left before the process is old enough to be
+// reviewed, or before retrying after some failure.
+func (s *ProcState) Wait(p *Proc) (wait time.Duration) {
+	if s == nil {
+		return 0
+	}
+	if uptime, err := Uptime(); err == nil {
+		wait = s.MinAge - p.Age(uptime)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, found := s.entries[p.Pid]; found && e.Match(p) {
+		if retry := time.Until(e.RetryAt); retry > wait {
+			wait = retry
+		}
+	}
+	return
+}
+
+// Fail records the failure adjusting the processes of the group and returns
+// the number of consecutive failures. The next retry is delayed, twice as
+// long after each failure.
+func (s *ProcState) Fail(job *ProcGroupJob, err error) (failures int, retry time.Duration) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range job.Jobs {
+		e := s.entry(j.Proc)
+		e.Failures++
+		e.LastError = err.Error()
+		if e.Failures > failures {
+			failures = e.Failures
+		}
+	}
+	retry = backoffDelay(failures)
+	retryAt := time.Now().Add(retry)
+	for _, j := range job.Jobs {
+		s.entry(j.Proc).RetryAt = retryAt
+	}
+	s.group(job, false)
+	status := s.groups[job.Pgrp]
+	status.Failures = failures
+	status.RetryAt = &retryAt
+	status.GaveUp = failures >= maxFailures
+	status.Error = err.Error()
+	return
+}
+
+// backoffDelay returns the delay before retrying after failures, twice as
+// long after each failure, up to backoffMax.
+func backoffDelay(failures int) time.Duration {
+	delay := backoffBase
+	for i := 1; i < failures && delay < backoffMax; i++ {
+		delay *= 2
+	}
+	if delay > backoffMax {
+		delay = backoffMax
+	}
+	return delay
+}
+
+// Succeed forgets the previous failures of the processes of the group.
+func (s *ProcState) Succeed(job *ProcGroupJob) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range job.Jobs {
+		e := s.entry(j.Proc)
+		e.Failures = 0
+		e.RetryAt = time.Time{}
+		e.LastError = ""
+	}
+	if status, found := s.groups[job.Pgrp]; found {
+		status.Failures = 0
+		status.RetryAt = nil
+		status.GaveUp = false
+		status.Error = ""
+	}
+}
+
+// Apply records what was applied to the process group, from the rule of its
+// leader.
+func (s *ProcState) Apply(job *ProcGroupJob) {
+	if s == nil || job.leader == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.entry(job.leader.Proc)
+	rule := job.leader.Rule
+	OverrideBase(rule.BaseProfile, &e.Applied)
+	s.realtime(job)
+	if e.Boosted && !(job.leader.Proc.Foreground()) { // restored
+		e.Applied.Nice, e.Applied.IOClass, e.Applied.IONice = rule.Nice, rule.IOClass, rule.IONice
+		e.Boosted = false
+	}
+}
+
+// Boost records that the process is boosted with the foreground profile.
+func (s *ProcState) Boost(p *Proc) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entry(p).Boosted = true
+}
+
+// Boosted returns whether the process was boosted and not restored yet.
+func (s *ProcState) Boosted(p *Proc) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, found := s.entries[p.Pid]
+	return found && e.Match(p) && e.Boosted
+}
+
+// Pinned returns the attributes of the process that must not be enforced
+// anymore, because they changed by hand since last applied.
+func (s *ProcState) Pinned(p *Proc, runtime BaseProfile) Rule {
+	if s == nil {
+		return Rule{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.entry(p)
+	if changed, count := Diff(e.Applied, runtime); count > 0 {
+		ResetMatching(&changed, &e.Applied) // do not detect them again
+		UpdateRule(changed, &e.Pinned)
+		if viper.GetBool("verbose") {
+			inform("", fmt.Sprintf(
+				"%s[%d]: changed by hand, pinning %v", p.Comm, p.Pid, ToInterface(changed),
+			))
+		}
+	}
+	return e.Pinned
+}
+
+// Pin stops enforcing any rule upon the process group of pid, until unpinned
+// or until the process exits.
+func (s *ProcState) Pin(p *Proc) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entry(p).Manual = true
+}
+
+// Unpin enforces again the rule upon the process, forgetting all the pinned
+// attributes.
+func (s *ProcState) Unpin(p *Proc) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.entry(p)
+	e.Manual = false
+	e.Applied = BaseProfile{}
+	e.Pinned = Rule{}
+	for _, other := range s.entries { // review the process group again
+		if other.Pgrp == e.Pgrp {
+			other.Settled = false
+		}
+	}
+}
+
+// GivenUp returns the processes given up since last call, sorted by pid.
+func (s *ProcState) GivenUp() (result []string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var pids []int
+	for pid, e := range s.entries {
+		if e.GaveUp() && !(s.reported[pid]) {
+			s.reported[pid] = true
+			pids = append(pids, pid)
+		}
+	}
+	sort.Ints(pids)
+	for _, pid := range pids {
+		e := s.entries[pid]
+		result = append(result, fmt.Sprintf("%s[%d]: %s", e.Comm, pid, e.LastError))
+	}
+	return
+}
+
+// Ignore marks the process without any rule as settled, not to be reviewed
+// again until reset.
+func (s *ProcState) Ignore(p *Proc) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.entry(p)
+	e.Settled = true
+	e.Realtime = false // no rule anymore
+}
+
+// Match records that the process matches some rule, and returns whether it
+// was not recorded yet.
+func (s *ProcState) Match(p *Proc) bool {
+	if s == nil {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.entry(p)
+	if e.Matched {
+		return false
+	}
+	e.Matched = true
+	return true
+}
+
+// Settle marks the processes of the group as matching their rule.
+func (s *ProcState) Settle(job *ProcGroupJob) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range job.Jobs {
+		e := s.entry(j.Proc)
+		e.Settled = true
+		if !(j.Proc.Foreground()) { // nothing to restore
+			e.Boosted = false
+		}
+		e.Threads = 0
+		if job.leader != nil && len(job.leader.Rule.Threads) > 0 {
+			e.Threads = j.Proc.NumThreads
+		}
+	}
+	s.realtime(job)
+	s.group(job, true)
+}
+
+// realtime records whether the rule of the group moves its processes to some
+// realtime class, since the rule may change on reload or with its variants.
+func (s *ProcState) realtime(job *ProcGroupJob) {
+	realtime := false
+	if job.leader != nil {
+		sched := job.leader.Rule.Sched
+		realtime = sched == "fifo" || sched == "rr"
+	}
+	for _, j := range job.Jobs {
+		s.entry(j.Proc).Realtime = realtime
+	}
+}
+
+// Realtime returns the pids of the processes that their rule moved to some
+// realtime class, sorted.
+func (s *ProcState) Realtime() (result []int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for pid, e := range s.entries {
+		if e.Realtime {
+			result = append(result, pid)
+		}
+	}
+	sort.Ints(result)
+	return
+}
+
+// Demote records that the process was demoted from its realtime class, and
+// must not be promoted again until time until.
+func (s *ProcState) Demote(p *Proc, until time.Time) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.entry(p)
+	e.Demoted = until
+	// not changed by hand
+	e.Applied.Sched, e.Applied.RTPrio = "other", 0
+}
+
+// Demoted returns whether the process was demoted and must not be promoted
+// again yet.
+func (s *ProcState) Demoted(p *Proc) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, found := s.entries[p.Pid]
+	return found && e.Match(p) && time.Now().Before(e.Demoted)
+}
+
+// Expire forgets the demotions whose cooldown ended, and returns whether some
+// process group must be reviewed again.
+func (s *ProcState) Expire(now time.Time) (expired bool) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.entries {
+		if e.Demoted.IsZero() || now.Before(e.Demoted) {
+			continue
+		}
+		e.Demoted, expired = time.Time{}, true
+		for _, other := range s.entries { // review the process group again
+			if other.Pgrp == e.Pgrp {
+				other.Settled = false
+			}
+		}
+	}
+	return
+}
+
+// Demotions returns the processes demoted and not promoted again yet, sorted
+// by pid.
+func (s *ProcState) Demotions() (result []string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var pids []int
+	for pid, e := range s.entries {
+		if !(e.Demoted.IsZero()) {
+			pids = append(pids, pid)
+		}
+	}
+	sort.Ints(pids)
+	for _, pid := range pids {
+		e := s.entries[pid]
+		result = append(result, fmt.Sprintf(
+			"%s[%d] until %s", e.Comm, pid, e.Demoted.Format("15:04:05"),
+		))
+	}
+	return
+}
+
+// Unsettle marks the processes of the group as diverging from their rule.
+func (s *ProcState) Unsettle(job *ProcGroupJob) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range job.Jobs {
+		s.entry(j.Proc).Settled = false
+	}
+	s.group(job, false)
+}
+
+// Prune drops the entries of the processes that are not running anymore.
+func (s *ProcState) Prune(procs []*Proc) {
+	if s == nil {
+		return
+	}
+	running := make(map[int]*Proc)
+	for _, p := range procs {
+		running[p.Pid] = p
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for pid, e := range s.entries {
+		if p, found := running[pid]; !found || !(e.Match(p)) {
+			delete(s.entries, pid)
+			delete(s.reported, pid)
+		}
+	}
+	s.pruneGroups()
+}
+
+// pruneGroups drops the process groups without any known process.
+func (s *ProcState) pruneGroups() {
+	alive := make(map[int]bool)
+	for _, e := range s.entries {
+		alive[e.Pgrp] = true
+	}
+	for pgrp := range s.groups {
+		if !(alive[pgrp]) {
+			delete(s.groups, pgrp)
+		}
+	}
+}
+
+// Forget drops the entry of the process that exited.
+func (s *ProcState) Forget(pid int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.reported, pid)
+	if e, found := s.entries[pid]; found {
+		delete(s.entries, pid)
+		if status, found := s.groups[e.Pgrp]; found {
+			status.Pids = Filter(status.Pids, func(p int) bool { return p != pid })
+			if len(status.Pids) == 0 {
+				delete(s.groups, e.Pgrp)
+			}
+		}
+	}
+}
+
+// Reset forgets whether the processes were settled or failed, in order to
+// review them all again. Pinned attributes are kept, and so are the processes
+// given up on, until they exit.
+func (s *ProcState) Reset() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.entries {
+		if e.GaveUp() {
+			continue
+		}
+		e.Settled = false
+		e.Failures = 0
+		e.RetryAt = time.Time{}
+		e.LastError = ""
+	}
+	for pgrp, status := range s.groups {
+		if !(status.GaveUp) {
+			delete(s.groups, pgrp)
+		}
+	}
+}
+
+// Len returns the number of known processes.
+func (s *ProcState) Len() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// Groups returns the status of the managed process groups, sorted by pgrp.
+func (s *ProcState) Groups() (result []GroupStatus) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result = make([]GroupStatus, 0, len(s.groups))
+	for _, status := range s.groups {
+		result = append(result, *status)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Pgrp < result[j].Pgrp
+	})
+	return
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
@@ -0,0 +1,250 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newProc returns some process of the pgrp group, running comm since start.
+func newProc(pid, pgrp int, comm string, start uint64) *Proc {
+	return &Proc{ProcStat: ProcStat{Pid: pid, Pgrp: pgrp, Comm: comm, StartTime: start}}
+}
+
+// settledState returns some state where the processes have been settled.
+func settledState(procs ...*Proc) *ProcState {
+	s := NewProcState(0)
+	job := &ProcGroupJob{}
+	for _, p := range procs {
+		job.Jobs = append(job.Jobs, &ProcJob{Proc: p})
+	}
+	s.Settle(job)
+	return s
+}
+
+func pids(procs []*Proc) (result []int) {
+	for _, p := range procs {
+		result = append(result, p.Pid)
+	}
+	return
+}
+
+func TestProcStateSelect(t *testing.T) {
+	leader, member := newProc(10, 10, "firefox", 100), newProc(11, 10, "firefox", 110)
+	other := newProc(20, 20, "mpv", 200)
+	tests := []struct {
+		name    string
+		settled []*Proc
+		procs   []*Proc
+		want    []int
+	}{
+		{"unknown", nil, []*Proc{leader, member, other}, []int{10, 11, 20}},
+		{"settled group", []*Proc{leader, member}, []*Proc{leader, member, other}, []int{20}},
+		{"all settled", []*Proc{leader, member, other}, []*Proc{leader, member, other}, nil},
+		{"new member", []*Proc{leader, member}, []*Proc{leader, member, newProc(12, 10, "firefox", 120)}, []int{10, 11, 12}},
+		{"reused pid", []*Proc{leader, member}, []*Proc{leader, newProc(11, 10, "firefox", 300)}, []int{10, 11}},
+		{"renamed", []*Proc{leader, member}, []*Proc{leader, newProc(11, 10, "Web Content", 110)}, []int{10, 11}},
+		{"moved member", []*Proc{leader, other}, []*Proc{leader, newProc(20, 10, "mpv", 200)}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ready, deferred := settledState(tt.settled...).Select(tt.procs)
+			if got := pids(ready); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if len(deferred) > 0 {
+				t.Errorf("deferred %v without minimum age", pids(deferred))
+			}
+		})
+	}
+}
+
+func TestProcStateSelectNil(t *testing.T) {
+	var s *ProcState
+	procs := []*Proc{newProc(10, 10, "firefox", 100)}
+	if ready, _ := s.Select(procs); !reflect.DeepEqual(ready, procs) {
+		t.Errorf("got %v, want all processes", pids(ready))
+	}
+}
+
+func TestProcStatePrune(t *testing.T) {
+	leader, member := newProc(10, 10, "firefox", 100), newProc(11, 10, "firefox", 110)
+	other := newProc(20, 20, "mpv", 200)
+	tests := []struct {
+		name    string
+		running []*Proc
+		want    []int // ready when running again
+	}{
+		{"all running", []*Proc{leader, member, other}, nil},
+		{"exited", []*Proc{leader, other}, []int{10, 11}},
+		{"reused pid", []*Proc{leader, member, newProc(20, 20, "bash", 500)}, []int{20}},
+		{"none running", nil, []int{10, 11, 20}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := settledState(leader, member, other)
+			s.Prune(tt.running)
+			ready, _ := s.Select([]*Proc{leader, member, other})
+			if got := pids(ready); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcStateForget(t *testing.T) {
+	leader, member := newProc(10, 10, "firefox", 100), newProc(11, 10, "firefox", 110)
+	s := settledState(leader, member)
+	s.Forget(11)
+	s.Forget(42) // unknown
+	if got := s.Len(); got != 1 {
+		t.Errorf("got %d entries, want 1", got)
+	}
+	ready, _ := s.Select([]*Proc{leader, member})
+	if got, want := pids(ready), []int{10, 11}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// reset processes are reviewed again
+	s = settledState(leader, member)
+	s.Reset()
+	ready, _ = s.Select([]*Proc{leader, member})
+	if got, want := pids(ready), []int{10, 11}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v after reset, want %v", got, want)
+	}
+}
+
+func TestProcStateResetGivenUp(t *testing.T) {
+	failing, other := newProc(10, 10, "firefox", 100), newProc(20, 20, "chrome", 200)
+	s := settledState(other)
+	job := leaderJob(failing, BaseProfile{Nice: 5})
+	for i := 0; i < maxFailures; i++ {
+		s.Fail(job, ErrFailure)
+	}
+	if got := s.GivenUp(); len(got) != 1 {
+		t.Fatalf("got %v given up, want firefox[10]", got)
+	}
+	s.Reset()
+	ready, _ := s.Select([]*Proc{failing, other})
+	if got, want := pids(ready), []int{20}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v after reset, want %v", got, want)
+	}
+	if got := s.GivenUp(); len(got) != 0 {
+		t.Errorf("got %v reported again after reset", got)
+	}
+	if groups := s.Groups(); len(groups) != 1 || !(groups[0].GaveUp) {
+		t.Errorf("got groups %+v after reset, want given up group", groups)
+	}
+	// until the process exits
+	s.Forget(10)
+	ready, _ = s.Select([]*Proc{failing})
+	if got, want := pids(ready), []int{10}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v after exit, want %v", got, want)
+	}
+}
+
+// leaderJob returns the job of the group led by p, whose rule is profile.
+func leaderJob(p *Proc, profile BaseProfile) *ProcGroupJob {
+	leader := &ProcJob{Proc: p, Rule: Rule{BaseProfile: profile}}
+	return &ProcGroupJob{Pgrp: p.Pgrp, Pids: []int{p.Pid}, Jobs: []*ProcJob{leader}, leader: leader}
+}
+
+func TestProcStatePinned(t *testing.T) {
+	tests := []struct {
+		name    string
+		applied BaseProfile
+		runtime BaseProfile
+		want    []string
+	}{
+		{"unchanged", BaseProfile{Nice: 5, OomScoreAdj: 100}, BaseProfile{Nice: 5, OomScoreAdj: 100}, []string{}},
+		{"nothing applied", BaseProfile{}, BaseProfile{Nice: -3}, []string{}},
+		{"reniced", BaseProfile{Nice: 5, OomScoreAdj: 100}, BaseProfile{Nice: -3, OomScoreAdj: 100}, []string{"nice"}},
+		{"reset", BaseProfile{Nice: 5}, BaseProfile{}, []string{"nice"}},
+		{"many", BaseProfile{Nice: 5, IOClass: "idle"}, BaseProfile{Nice: 0, IOClass: "best-effort"}, []string{"ioclass", "nice"}},
+		{"not applied", BaseProfile{Nice: 5}, BaseProfile{Nice: 5, OomScoreAdj: 500}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newProc(10, 10, "firefox", 100)
+			s := NewProcState(0)
+			s.Apply(leaderJob(p, tt.applied))
+			got := Keys(ToInterface(s.Pinned(p, tt.runtime).BaseProfile))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			// pinned attributes stay pinned, even when restored by hand
+			if again := Keys(ToInterface(s.Pinned(p, tt.applied).BaseProfile)); !reflect.DeepEqual(again, tt.want) {
+				t.Errorf("got %v once restored, want %v", again, tt.want)
+			}
+			s.Unpin(p)
+			if got := Keys(ToInterface(s.Pinned(p, tt.runtime).BaseProfile)); len(got) > 0 {
+				t.Errorf("got %v once unpinned, want none", got)
+			}
+		})
+	}
+}
+
+func TestProcStatePin(t *testing.T) {
+	leader, member := newProc(10, 10, "firefox", 100), newProc(11, 10, "firefox", 110)
+	other := newProc(20, 20, "mpv", 200)
+	s := NewProcState(0)
+	s.Pin(member)
+	ready, _ := s.Select([]*Proc{leader, member, other})
+	if got, want := pids(ready), []int{20}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// pinned until the process exits, even when executed or renamed
+	renamed := newProc(11, 10, "Web Content", 110)
+	ready, _ = s.Select([]*Proc{leader, renamed, other})
+	if got, want := pids(ready), []int{20}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v once renamed, want %v", got, want)
+	}
+	s.Unpin(member)
+	ready, _ = s.Select([]*Proc{leader, member, other})
+	if got, want := pids(ready), []int{10, 11, 20}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v once unpinned, want %v", got, want)
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{math.MinInt, backoffBase},
+		{-1, backoffBase},
+		{0, backoffBase},
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{4, 4 * time.Minute},
+		{maxFailures, 8 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, backoffMax}, // 64 minutes
+		{64, backoffMax},
+		{math.MaxInt, backoffMax},
+	}
+	for _, tt := range tests {
+		if got := backoffDelay(tt.failures); got != tt.want {
+			t.Errorf("%d failures: got %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
@@ -101,6 +101,21 @@ func setCapabilities(enable bool) error {
 	return nil
 }
 
+// raiseCapability sets the effective flag for the capability, when permitted.
+func raiseCapability(val cap.Value) {
+	c := cap.GetProc()
+	if permitted, err := c.GetFlag(cap.Permitted, val); err != nil || !(permitted) {
+		return
+	}
+	if err := c.SetFlag(cap.Effective, true, val); err != nil {
+		debug(fmt.Errorf("%w: unable to change flag: %v", ErrFailure, err))
+		return
+	}
+	if err := c.SetProc(); err != nil {
+		debug(fmt.Errorf("%w: unable to update %q: %v", ErrFailure, c, err))
+	}
+}
+
 func setAmbient(enable bool) (err error) {
 	if e := cap.SetAmbient(enable, inheritable...); e != nil {
 		err = fmt.Errorf("%w: unable to change ambient set: %v", ErrFailure, e)
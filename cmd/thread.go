@@ -0,0 +1,314 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Thread is some thread of a process, read from its task directory.
+type Thread struct {
+	Tid         int    `json:"tid"`
+	Pid         int    `json:"pid"` // thread group id
+	Comm        string `json:"comm"`
+	State       string `json:"state"`
+	Nice        int    `json:"nice"`
+	RTPrio      int    `json:"rtprio"`
+	Policy      int    `json:"policy"`
+	IOPrioClass int    `json:"ioprio_class"`
+	IOPrioData  int    `json:"ionice"`
+}
+
+// ReadThreads returns the threads of the process, sorted by thread id.
+func (p *Proc) ReadThreads() (result []*Thread, err error) {
+	files, err := procFS.TaskStatFiles(p.Pid)
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil { // thread is gone
+			continue
+		}
+		var stat ProcStat
+		if err := stat.UnmarshalText(bytes.TrimSpace(data)); err != nil {
+			return nil, err
+		}
+		t := &Thread{
+			Tid:    stat.Pid,
+			Pid:    p.Pid,
+			Comm:   stat.Comm,
+			State:  stat.State,
+			Nice:   stat.Nice,
+			RTPrio: stat.RTPrio,
+			Policy: stat.Policy,
+		}
+		if procFS.Live() {
+			if ioprio, err := IOPrio_Get(t.Tid); err == nil {
+				IOPrio_Split(ioprio, &t.IOPrioClass, &t.IOPrioData)
+			}
+		}
+		result = append(result, t)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Tid < result[j].Tid
+	})
+	return
+}
+
+func (t *Thread) Sched() string {
+	return CPUSched[t.Policy]
+}
+
+func (t *Thread) IOClass() string {
+	return IO.Class[t.IOPrioClass]
+}
+
+func (t *Thread) Runtime() BaseProfile {
+	return BaseProfile{
+		Nice:    t.Nice,
+		Sched:   t.Sched(),
+		RTPrio:  t.RTPrio,
+		IOClass: t.IOClass(),
+		IONice:  t.IOPrioData,
+	}
+}
+
+func (t *Thread) String() string {
+	return fmt.Sprintf(
+		"{Tid: %v, Pid: %v, Comm: %v, State: %v, Nice: %v, Sched: %v, RTPrio: %v, IOClass: %v, IONice: %v}",
+		t.Tid, t.Pid, t.Comm, t.State, t.Nice, t.Sched(), t.RTPrio, t.IOClass(), t.IOPrioData,
+	)
+}
+
+func (t *Thread) Raw() string {
+	return strings.TrimSuffix(
+		fmt.Sprintln(
+			t.Tid, t.Pid, t.State, t.Nice, t.Policy, t.RTPrio,
+			t.IOPrioClass, t.IOPrioData, t.Comm,
+		),
+		"\n",
+	)
+}
+
+func (t *Thread) Values() string {
+	return fmt.Sprintf("[%d,%d,%q,%d,%q,%d,%q,%d,%q]",
+		t.Tid, t.Pid, t.State, t.Nice, t.Sched(), t.RTPrio,
+		t.IOClass(), t.IOPrioData, t.Comm,
+	)
+}
+
+type ThreadFormatter func(t *Thread) string
+
+func GetThreadFormatter(format string) ThreadFormatter {
+	switch strings.ToLower(format) {
+	case "raw":
+		return func(t *Thread) string { return t.Raw() }
+	case "values":
+		return func(t *Thread) string { return t.Values() }
+	default:
+		return func(t *Thread) string { return t.String() }
+	}
+}
+
+// ThreadProfile is the profile overriding the rule for some threads. Unlike
+// in presets, nice is set even when zero, so that threads can keep the
+// default nice value of processes that their rule renices.
+type ThreadProfile struct {
+	Nice        *int   `yaml:"nice,omitempty" json:"nice,omitempty"`
+	Sched       string `yaml:"sched,omitempty" json:"sched,omitempty"`
+	RTPrio      int    `yaml:"rtprio,omitempty" json:"rtprio,omitempty"`
+	IOClass     string `yaml:"ioclass,omitempty" json:"ioclass,omitempty"`
+	IONice      int    `yaml:"ionice,omitempty" json:"ionice,omitempty"`
+	OomScoreAdj int    `yaml:"oom_score_adj,omitempty" json:"oom_score_adj,omitempty"`
+}
+
+// Base returns the profile, without nice when zero.
+func (p ThreadProfile) Base() BaseProfile {
+	base := BaseProfile{
+		Sched:       p.Sched,
+		RTPrio:      p.RTPrio,
+		IOClass:     p.IOClass,
+		IONice:      p.IONice,
+		OomScoreAdj: p.OomScoreAdj,
+	}
+	if p.Nice != nil {
+		base.Nice = *p.Nice
+	}
+	return base
+}
+
+// Realtime returns whether the profile moves threads to some realtime class.
+func (p ThreadProfile) Realtime() bool {
+	return p.Sched == "fifo" || p.Sched == "rr"
+}
+
+// Unprivileged resets the attributes that require privileges, unless
+// granted, and returns their names.
+func (p *ThreadProfile) Unprivileged(granted ThreadProfile) (rejected []string) {
+	base := p.Base()
+	rejected = base.Unprivileged(granted.Base())
+	nice := p.Nice
+	if Contains(rejected, "nice") {
+		nice = nil
+	}
+	*p = ThreadProfile{
+		Nice:        nice,
+		Sched:       base.Sched,
+		RTPrio:      base.RTPrio,
+		IOClass:     base.IOClass,
+		IONice:      base.IONice,
+		OomScoreAdj: base.OomScoreAdj,
+	}
+	return
+}
+
+// ThreadProfiles maps thread name patterns, as in path.Match, to the
+// profiles overriding the rule for the matching threads.
+type ThreadProfiles map[string]ThreadProfile
+
+// Validate checks the patterns and the profiles. Realtime classes require
+// some realtime rule, whose processes the realtime watchdog samples.
+func (tp ThreadProfiles) Validate(rule BaseProfile) error {
+	for pattern, profile := range tp {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%w: threads: %q: %v", ErrInvalid, pattern, err)
+		}
+		if profile.OomScoreAdj != 0 { // per process
+			return fmt.Errorf("%w: threads: %q: oom_score_adj", ErrInvalid, pattern)
+		}
+		if profile.Realtime() && rule.Sched != "fifo" && rule.Sched != "rr" {
+			return fmt.Errorf("%w: threads: %q: sched %s without realtime rule", ErrInvalid, pattern, profile.Sched)
+		}
+	}
+	return nil
+}
+
+// Match returns the profile for the thread name. Names matching some pattern
+// exactly come first, then patterns are tried in lexical order.
+func (tp ThreadProfiles) Match(name string) (ThreadProfile, bool) {
+	if profile, found := tp[name]; found {
+		return profile, true
+	}
+	patterns := make([]string, 0, len(tp))
+	for pattern := range tp {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return tp[pattern], true
+		}
+	}
+	return ThreadProfile{}, false
+}
+
+// Credentials returns the credentials that the profiles require.
+func (tp ThreadProfiles) Credentials() (result []string) {
+	for _, profile := range tp {
+		if profile.Realtime() {
+			result = append(result, "sched")
+		}
+		if profile.IOClass == "realtime" {
+			result = append(result, "ioclass")
+		}
+	}
+	return
+}
+
+// threadOverride is some thread with the profile overriding the rule of its
+// process.
+type threadOverride struct {
+	*Thread
+	Profile ThreadProfile
+}
+
+// reviewThreads records the threads of the processes in the group matching
+// some profile, and returns the count of their attributes to adjust.
+// Realtime classes are not granted while the leader is demoted.
+func (job *ProcGroupJob) reviewThreads(threads ThreadProfiles, demoted bool) (count int) {
+	job.threads = nil
+	if len(threads) == 0 {
+		return
+	}
+	for _, j := range job.Jobs {
+		tasks, err := j.Proc.ReadThreads()
+		if err != nil {
+			debug(err)
+			continue
+		}
+		for _, t := range tasks {
+			profile, found := threads.Match(t.Comm)
+			if !(found) {
+				continue
+			}
+			if demoted {
+				profile.Sched, profile.RTPrio = "", 0
+			}
+			job.threads = append(job.threads, threadOverride{Thread: t, Profile: profile})
+			_, n := Diff(profile.Base(), t.Runtime())
+			if profile.Nice != nil && *profile.Nice == 0 && t.Nice != 0 {
+				n++ // back to default nice value
+			}
+			count += n
+		}
+	}
+	return
+}
+
+// AdjustThread sets the attributes of the profile for the thread only. As
+// the process wide commands also set all the threads, it runs after them.
+func (job *ProcJob) AdjustThread(o threadOverride) error {
+	r, tid := o.Profile, o.Tid
+	if r.Nice != nil {
+		job.AddProfileCommand("nice", renice(*r.Nice, tid))
+	}
+	if r.Sched != "" || r.RTPrio != 0 {
+		sched, rtprio := r.Sched, 0
+		if sched == "" {
+			sched = o.Sched()
+		}
+		if sched == "fifo" || sched == "rr" {
+			rtprio = r.RTPrio
+			if rtprio == 0 {
+				rtprio = o.RTPrio
+			}
+			if rtprio == 0 {
+				rtprio = 1
+			}
+		}
+		job.AddProfileCommand("sched", chrtThread(sched, rtprio, tid))
+	}
+	if r.IOClass != "" || r.IONice != 0 {
+		class, level := r.IOClass, -1
+		if r.IONice != 0 && (class == "realtime" || class == "best-effort" ||
+			(class == "" && (o.IOPrioClass == 1 || o.IOPrioClass == 2))) {
+			level = r.IONice
+		}
+		if class != "" || level != -1 {
+			job.AddProfileCommand("ioclass", ionice(class, level, tid))
+		}
+	}
+	return nil
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
@@ -0,0 +1,121 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"errors"
+	"testing"
+)
+
+// niceProfile returns some thread profile setting nice.
+func niceProfile(nice int) ThreadProfile {
+	return ThreadProfile{Nice: &nice}
+}
+
+func TestThreadProfilesMatch(t *testing.T) {
+	tp := ThreadProfiles{
+		"worker":     niceProfile(1),
+		"worker*":    niceProfile(2),
+		"*er":        niceProfile(3),
+		"DOM Worker": niceProfile(4),
+		"pool-[0-9]": niceProfile(5),
+		"kworker/*":  niceProfile(6),
+		"gmain?":     niceProfile(7),
+	}
+	tests := []struct {
+		name  string
+		want  int
+		found bool
+	}{
+		{"worker", 1, true},         // exact name first
+		{"worker-1", 2, true},       // worker* only
+		{"watcher", 3, true},        // *er only
+		{"workerer", 3, true},       // lexical order, *er first
+		{"DOM Worker", 4, true},     // spaces
+		{"pool-3", 5, true},         // class
+		{"pool-12", 0, false},       // one character only
+		{"kworker/0:1", 6, true},    // slash
+		{"gmain", 0, false},         // one character required
+		{"gmainx", 7, true},         // any character
+		{"Workers", 0, false},       // case sensitive
+		{"", 0, false},              // no name
+		{"firefox", 0, false},       // no pattern
+		{"*er", 3, true},            // pattern as name
+		{"kworker/0:1/x", 0, false}, // star stops at slash
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := tp.Match(tt.name)
+			if nice := got.Base().Nice; found != tt.found || nice != tt.want {
+				t.Errorf("got nice %d, found %v, want nice %d, found %v", nice, found, tt.want, tt.found)
+			}
+		})
+	}
+	if _, found := (ThreadProfiles{}).Match("worker"); found {
+		t.Error("empty profiles: found some profile")
+	}
+	if _, found := ThreadProfiles(nil).Match("worker"); found {
+		t.Error("nil profiles: found some profile")
+	}
+}
+
+func TestThreadProfilesValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    BaseProfile
+		profile ThreadProfile
+		wantErr error
+	}{
+		{"nice", BaseProfile{Nice: 2}, niceProfile(0), nil},
+		{"oom_score_adj", BaseProfile{}, ThreadProfile{OomScoreAdj: 100}, ErrInvalid},
+		{"realtime rule", BaseProfile{Sched: "rr"}, ThreadProfile{Sched: "fifo"}, nil},
+		{"no realtime rule", BaseProfile{Nice: 2}, ThreadProfile{Sched: "fifo"}, ErrInvalid},
+		{"other class", BaseProfile{}, ThreadProfile{Sched: "batch"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ThreadProfiles{"worker": tt.profile}.Validate(tt.rule)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAdjustThreadNice(t *testing.T) {
+	thread := &Thread{Tid: 42, Nice: 2}
+	tests := []struct {
+		name    string
+		profile ThreadProfile
+		want    int // commands
+	}{
+		{"default nice", niceProfile(0), 1},
+		{"nice", niceProfile(-5), 1},
+		{"no nice", ThreadProfile{}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := NewProcJob(newProc(42, 42, "firefox", 100))
+			job.AdjustThread(threadOverride{Thread: thread, Profile: tt.profile})
+			if got := len(job.Commands); got != tt.want {
+				t.Errorf("got %d commands, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
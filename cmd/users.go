@@ -0,0 +1,207 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// userCache is the cache built for some user, with the modification time of
+// the user configuration file, when read.
+type userCache struct {
+	pc      *PresetCache
+	modTime time.Time
+}
+
+// UserCaches resolves the presets of each process with the configuration of
+// its owner, layered over the site and vendor configuration of Base.
+type UserCaches struct {
+	Base    *PresetCache
+	PerUser bool
+	mu      sync.Mutex
+	caches  map[int]userCache
+}
+
+// NewUserCaches returns the caches built upon base. Unless perUser, base
+// applies to all processes.
+func NewUserCaches(base *PresetCache, perUser bool) *UserCaches {
+	return &UserCaches{
+		Base:    base,
+		PerUser: perUser,
+		caches:  make(map[int]userCache),
+	}
+}
+
+// userConfigFile returns the path of the configuration file of user with
+// home directory.
+func userConfigFile(home string) string {
+	return filepath.Join(home, ".config", prog, confName+"."+confType)
+}
+
+// build returns the presets of Base, plus those from the configuration file
+// of the user. The users.override option allows user presets to replace
+// system ones.
+func (uc *UserCaches) build(path string, owner string) (*PresetCache, error) {
+	own := NewPresetCache()
+	if err := own.LoadFromDirs([]string{filepath.Dir(path)}); err != nil {
+		return nil, err
+	}
+	pc := uc.Base.Merge(&own, viper.GetBool("users.override"))
+	if err := pc.Validate(); err != nil {
+		return nil, err
+	}
+	pc.restrict = &restriction{
+		granted: uc.Base,
+		owner:   owner,
+		logged:  make(map[string]bool),
+	}
+	return pc, nil
+}
+
+// restriction limits the rules resolved with the presets of some user to the
+// attributes that the user could set without privileges, unless Base grants
+// them the same for the same request.
+type restriction struct {
+	granted *PresetCache
+	owner   string
+	mu      sync.Mutex
+	logged  map[string]bool // rules yet reported
+}
+
+// apply resets the attributes of the rule for input that require privileges
+// and are not granted, and reports them once per rule.
+func (rs *restriction) apply(input *Request, rule *Rule) {
+	if rs == nil {
+		return
+	}
+	granted, err := rs.granted.RawRule(input)
+	if err != nil || rs.granted.Expand(&granted) != nil {
+		granted = Rule{}
+	}
+	rejected := rule.Unprivileged(granted)
+	if len(rejected) == 0 {
+		return
+	}
+	key := input.Preset
+	if key == "auto" || key == "cgroup-only" {
+		key = input.Name
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if rs.logged[key] {
+		return
+	}
+	rs.logged[key] = true
+	nonfatal(fmt.Errorf(
+		"%w: user %s: %s: rejecting %s", ErrPermission, rs.owner, key, strings.Join(rejected, ", "),
+	))
+}
+
+// Owner returns the cache for the owner of the process. Base applies to
+// superuser processes, processes outside of user slices and users without
+// valid configuration file. The cache is built again when the file changes.
+func (uc *UserCaches) Owner(p *Proc) *PresetCache {
+	if !(uc.PerUser) || p.Uid == 0 || !(p.InUserSlice()) {
+		return uc.Base
+	}
+	home := p.Owner().HomeDir
+	if home == "" {
+		return uc.Base
+	}
+	path := userConfigFile(home)
+	info, err := os.Stat(path)
+	if err != nil {
+		return uc.Base
+	}
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	if cache, found := uc.caches[p.Uid]; found && cache.modTime.Equal(info.ModTime()) {
+		return cache.pc
+	}
+	if viper.GetBool("verbose") {
+		inform("cache", fmt.Sprintf("building presets for user %s...", p.Username()))
+	}
+	pc, err := uc.build(path, p.Username())
+	if err != nil {
+		warn(fmt.Errorf("%w: user %s: %v", ErrInvalid, p.Username(), err))
+		pc = uc.Base // do not report again until file changes
+	}
+	uc.caches[p.Uid] = userCache{pc: pc, modTime: info.ModTime()}
+	return pc
+}
+
+// split returns the processes per cache, in order of first process.
+func (uc *UserCaches) split(procs []*Proc) (caches []*PresetCache, batches [][]*Proc) {
+	index := make(map[*PresetCache]int)
+	for _, p := range procs {
+		pc := uc.Owner(p)
+		i, found := index[pc]
+		if !(found) {
+			i = len(caches)
+			index[pc] = i
+			caches = append(caches, pc)
+			batches = append(batches, nil)
+		}
+		batches[i] = append(batches[i], p)
+	}
+	return
+}
+
+// SendGroupJobs sends the process group jobs for procs, each process being
+// resolved with the cache of its owner.
+func (uc *UserCaches) SendGroupJobs(procs []*Proc, output chan<- *ProcGroupJob) {
+	caches, batches := uc.split(procs)
+	for i, pc := range caches {
+		pc.SendGroupJobs(batches[i], output)
+	}
+}
+
+// GenerateGroupJobs is like PresetCache.GenerateGroupJobs, with the cache of
+// the owner of each process.
+func (uc *UserCaches) GenerateGroupJobs(inputs <-chan []*Proc, output chan<- *ProcGroupJob, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for procs := range inputs {
+		uc.SendGroupJobs(procs, output)
+	}
+	close(output)
+}
+
+// ApplyPreset is like PresetCache.ApplyPreset, with the cache of the owner of
+// the process.
+func (uc *UserCaches) ApplyPreset(pid int, preset string, tag string, std *Streams) (*ProcGroupJob, error) {
+	found := SelectedProcs([]int{pid}, GetFilterer("all"))
+	if len(found) == 0 {
+		return nil, fmt.Errorf("%w: process %d", ErrNotFound, pid)
+	}
+	return uc.Owner(found[0]).ApplyPreset(pid, preset, tag, std)
+}
+
+// perUserScope returns whether the filter selects processes of other users,
+// then resolved with the cache of their owner.
+func perUserScope(filter ProcFilterer) bool {
+	pf, ok := filter.(ProcFilter)
+	return ok && (pf.Scope == "global" || pf.Scope == "all")
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
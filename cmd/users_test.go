@@ -0,0 +1,130 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// userProc returns some process of the user with uid and home directory,
+// running inside slice, once the user is known under uid.
+func userProc(uid int, home, slice string) *Proc {
+	p := newProc(1000+uid, 1000+uid, "firefox", 0)
+	p.Uid, p.Slice = uid, slice
+	owner := &user.User{Username: "user" + filepath.Base(home), HomeDir: home}
+	knownUsers.Lock()
+	knownUsers.users[uid] = knownUser{user: owner, since: time.Now()}
+	knownUsers.Unlock()
+	return p
+}
+
+// writeUserConfig writes content as the configuration file of the user with
+// home directory.
+func writeUserConfig(t *testing.T, home, content string) {
+	t.Helper()
+	path := userConfigFile(home)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUserCachesOwner(t *testing.T) {
+	defer viper.Set("confdirs", viper.GetStringSlice("confdirs"))
+	viper.Set("confdirs", []string{writeConfig(t, `presets:
+  appgroups:
+    none: {}
+  rules:
+    firefox:
+      nice: 1
+`)})
+	base, err := ReloadPresetCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		home    = t.TempDir()
+		empty   = t.TempDir()
+		invalid = t.TempDir()
+	)
+	const own = "presets:\n  rules:\n    firefox:\n      nice: 10\n    mpv:\n      nice: -5\n"
+	writeUserConfig(t, home, own)
+	writeUserConfig(t, invalid, "presets:\n  rules:\n    mpv:\n      profile: Video\n")
+	tests := []struct {
+		name     string
+		perUser  bool
+		override bool
+		p        *Proc
+		wantBase bool
+		wantNice int // of firefox rule
+	}{
+		{"user", true, false, userProc(1000, home, "user.slice"), false, 1},
+		{"user override", true, true, userProc(1000, home, "user.slice"), false, 10},
+		{"not per user", false, false, userProc(1000, home, "user.slice"), true, 1},
+		{"superuser", true, false, userProc(0, home, "user.slice"), true, 1},
+		{"system slice", true, false, userProc(1000, home, "system.slice"), true, 1},
+		{"no home", true, false, userProc(1003, "", "user.slice"), true, 1},
+		{"no config", true, false, userProc(1001, empty, "user.slice"), true, 1},
+		{"invalid config", true, false, userProc(1002, invalid, "user.slice"), true, 1},
+	}
+	defer viper.Set("users.override", viper.GetBool("users.override"))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("users.override", tt.override)
+			pc := NewUserCaches(base, tt.perUser).Owner(tt.p)
+			if (pc == base) != tt.wantBase {
+				t.Errorf("got base %v, want %v", pc == base, tt.wantBase)
+			}
+			if _, err := pc.Rule("mpv"); (err == nil) == tt.wantBase {
+				t.Errorf("got mpv rule error %v", err)
+			}
+			if rule, err := pc.Rule("firefox"); err != nil || rule.Nice != tt.wantNice {
+				t.Errorf("got firefox nice %d (%v), want %d", rule.Nice, err, tt.wantNice)
+			}
+		})
+	}
+}
+
+func TestUserCachesRebuild(t *testing.T) {
+	base := NewPresetCache()
+	home := t.TempDir()
+	writeUserConfig(t, home, "presets:\n  appgroups:\n    none: {}\n  rules:\n    mpv:\n      nice: -5\n")
+	uc := NewUserCaches(&base, true)
+	p := userProc(1000, home, "user.slice")
+	first := uc.Owner(p)
+	if again := uc.Owner(p); again != first {
+		t.Error("cache built again without change")
+	}
+	writeUserConfig(t, home, "presets:\n  appgroups:\n    none: {}\n  rules:\n    mpv:\n      nice: 7\n")
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(userConfigFile(home), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if rule, err := uc.Owner(p).Rule("mpv"); err != nil || rule.Nice != 7 {
+		t.Errorf("got mpv nice %d (%v) once changed, want 7", rule.Nice, err)
+	}
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
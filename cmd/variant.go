@@ -0,0 +1,168 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Variant holds the values that override those of some preset, while it
+// applies. Profile and cgroup keys are ignored for cgroups, profile key for
+// profiles.
+type Variant struct {
+	BaseProfile `yaml:",inline"`
+	BaseCgroup  `yaml:",inline"`
+	ProfileKey  string `yaml:"profile,omitempty" json:"profile,omitempty"`
+	CgroupKey   string `yaml:"cgroup,omitempty" json:"cgroup,omitempty"`
+}
+
+// IsZero returns whether the variant sets no value. Like in presets, zero
+// values are not set, so that they override nothing.
+func (v *Variant) IsZero() bool {
+	return *v == Variant{}
+}
+
+// Variants holds the optional variants of some preset.
+type Variants struct {
+	OnBattery *Variant `yaml:"on_battery,omitempty,flow" json:"on_battery,omitempty"`
+	OnAC      *Variant `yaml:"on_ac,omitempty,flow" json:"on_ac,omitempty"`
+	Schedule  []Window `yaml:"schedule,omitempty" json:"schedule,omitempty"`
+}
+
+// Active returns the variants that apply at time now, in order: the variant
+// for the power source, then the first schedule window open.
+func (v *Variants) Active(now time.Time) (result []*Variant) {
+	power := v.OnAC
+	if CurrentPowerSource() == powerBattery {
+		power = v.OnBattery
+	}
+	if power != nil {
+		result = append(result, power)
+	}
+	for i := range v.Schedule {
+		if v.Schedule[i].Open(now) {
+			result = append(result, &v.Schedule[i].Variant)
+			break
+		}
+	}
+	return
+}
+
+// List returns the variants set.
+func (v *Variants) List() (result []*Variant) {
+	for _, variant := range []*Variant{v.OnBattery, v.OnAC} {
+		if variant != nil {
+			result = append(result, variant)
+		}
+	}
+	for i := range v.Schedule {
+		result = append(result, &v.Schedule[i].Variant)
+	}
+	return
+}
+
+// applyCgroup applies the active variants, if any, to the cgroup.
+func applyCgroup(c *Cgroup, now time.Time) {
+	for _, v := range c.Variants.Active(now) {
+		OverrideBase(v.BaseCgroup, &c.BaseCgroup)
+	}
+	c.Variants = Variants{}
+}
+
+// applyProfile applies the active variants, if any, to the profile.
+func applyProfile(p *Profile, now time.Time) {
+	for _, v := range p.Variants.Active(now) {
+		OverrideBase(v.BaseProfile, &p.BaseProfile)
+		OverrideBase(v.BaseCgroup, &p.BaseCgroup)
+		if v.CgroupKey != "" {
+			p.CgroupKey = v.CgroupKey
+		}
+	}
+	p.Variants = Variants{}
+}
+
+// applyRule applies the active variants, if any, to the rule.
+func applyRule(r *Rule, now time.Time) {
+	for _, v := range r.Variants.Active(now) {
+		OverrideBase(v.BaseProfile, &r.BaseProfile)
+		OverrideBase(v.BaseCgroup, &r.BaseCgroup)
+		if v.ProfileKey != "" {
+			r.ProfileKey = v.ProfileKey
+		}
+		if v.CgroupKey != "" {
+			r.CgroupKey = v.CgroupKey
+		}
+	}
+	r.Variants = Variants{}
+}
+
+// validateVariants checks the schedule windows, that the variants set some
+// value, and that their profiles and cgroups exist.
+func (pc *PresetCache) validateVariants(v *Variants) error {
+	for i := range v.Schedule {
+		if err := v.Schedule[i].Validate(); err != nil {
+			return err
+		}
+	}
+	for _, variant := range v.List() {
+		if variant.IsZero() {
+			return fmt.Errorf("%w: variant sets no value", ErrInvalid)
+		}
+		if key := variant.ProfileKey; key != "" && !(pc.HasPreset("profile", key)) {
+			return fmt.Errorf("%w: profile %s", ErrNotFound, key)
+		}
+		if key := variant.CgroupKey; key != "" && !(pc.HasPreset("cgroup", key)) {
+			return fmt.Errorf("%w: cgroup %s", ErrNotFound, key)
+		}
+	}
+	return nil
+}
+
+// VariantSliceProperties returns, for the cgroups with variants, the
+// properties of their nicy slice. The properties that only some variant sets
+// are reset.
+func (pc *PresetCache) VariantSliceProperties() map[string][]string {
+	result := make(map[string][]string)
+	for key, cgroups := range pc.Cgroups {
+		raw := ActivePreset(cgroups)
+		variants := raw.Variants.List()
+		if len(variants) == 0 {
+			continue
+		}
+		cgroup, _ := pc.Cgroup(key)
+		properties := cgroup.ScopeProperties()
+		set := make(map[string]bool)
+		for _, property := range properties {
+			name, _, _ := strings.Cut(property, "=")
+			set[name] = true
+		}
+		for _, variant := range variants {
+			for _, property := range Properties(variant.BaseCgroup) {
+				if name, _, _ := strings.Cut(property, "="); !(set[name]) {
+					set[name] = true
+					properties = append(properties, name+"=") // reset
+				}
+			}
+		}
+		result[key] = properties
+	}
+	return result
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
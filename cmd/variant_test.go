@@ -0,0 +1,116 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+const variantConfig = `presets:
+  cgroups:
+    cpu33:
+      CPUQuota: 33%
+      on_battery:
+        CPUQuota: 10%
+    cpu50:
+      CPUQuota: 50%
+  appgroups:
+    none: {}
+    Heavy:
+      profile:
+        nice: 10
+      on_battery:
+        nice: 19
+        cgroup: cpu33
+  rules:
+    firefox:
+      nice: 1
+      on_battery:
+        nice: 5
+      on_ac:
+        cgroup: cpu50
+    make:
+      profile: Heavy
+`
+
+func TestVariants(t *testing.T) {
+	defer viper.Set("confdirs", viper.GetStringSlice("confdirs"))
+	viper.Set("confdirs", []string{writeConfig(t, variantConfig)})
+	pc, err := ReloadPresetCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		source        string
+		rule          string
+		wantNice      int
+		wantCgroupKey string
+		wantQuota     string
+	}{
+		{powerAC, "firefox", 1, "cpu50", "50%"},
+		{powerBattery, "firefox", 5, "", ""},
+		{powerAC, "make", 10, "", ""},
+		{powerBattery, "make", 19, "cpu33", "10%"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source+" "+tt.rule, func(t *testing.T) {
+			setPowerSource(t, tt.source)
+			rule, err := pc.Rule(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := pc.Expand(&rule); err != nil {
+				t.Fatal(err)
+			}
+			if rule.Nice != tt.wantNice || rule.CgroupKey != tt.wantCgroupKey || rule.CPUQuota != tt.wantQuota {
+				t.Errorf("got (%d, %q, %q), want (%d, %q, %q)",
+					rule.Nice, rule.CgroupKey, rule.CPUQuota,
+					tt.wantNice, tt.wantCgroupKey, tt.wantQuota)
+			}
+		})
+	}
+}
+
+func TestValidateVariants(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr error
+	}{
+		{"valid", variantConfig, nil},
+		{"rule profile", "presets:\n  appgroups:\n    none: {}\n  rules:\n    mpv:\n      on_battery:\n        profile: Video\n", ErrInvalid},
+		{"rule cgroup", "presets:\n  appgroups:\n    none: {}\n  rules:\n    mpv:\n      on_ac:\n        cgroup: cpu80\n", ErrInvalid},
+		{"profile cgroup", "presets:\n  appgroups:\n    none: {}\n    Video:\n      on_battery:\n        cgroup: cpu80\n", ErrInvalid},
+		{"zero nice", "presets:\n  appgroups:\n    none: {}\n  rules:\n    mpv:\n      nice: 5\n      on_ac:\n        nice: 0\n", ErrInvalid},
+		{"empty quota", "presets:\n  cgroups:\n    cpu33:\n      CPUQuota: 33%\n      on_ac:\n        CPUQuota: \"\"\n  appgroups:\n    none: {}\n", ErrInvalid},
+		{"empty window", "presets:\n  appgroups:\n    none: {}\n  rules:\n    mpv:\n      nice: 5\n      schedule:\n        - from: \"09:00\"\n          to: \"18:00\"\n", ErrInvalid},
+	}
+	defer viper.Set("confdirs", viper.GetStringSlice("confdirs"))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("confdirs", []string{writeConfig(t, tt.config)})
+			if _, err := ReloadPresetCache(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
@@ -0,0 +1,90 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// WatchDirs sends the name of the last file changed inside dirs, once no
+// more change happened during delay. Missing directories are not watched.
+func WatchDirs(ctx context.Context, dirs []string, delay time.Duration) (<-chan string, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("%w: watcher: %v", ErrFailure, err)
+	}
+	var count int
+	for _, dir := range dirs {
+		if !(exists(dir)) {
+			continue
+		}
+		if err := watcher.Add(dir); err != nil {
+			debug("cannot watch", dir+":", err)
+			continue
+		}
+		count++
+	}
+	if count == 0 {
+		watcher.Close()
+		return nil, fmt.Errorf("%w: no directory to watch", ErrNotFound)
+	}
+	ch := make(chan string)
+	go func() {
+		defer func() {
+			watcher.Close()
+			close(ch)
+		}()
+		var (
+			last  string
+			timer = time.NewTimer(delay)
+		)
+		timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if ev.Has(fsnotify.Chmod) && !(ev.Has(fsnotify.Write)) {
+					continue
+				}
+				last = ev.Name
+				timer.Reset(delay)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				nonfatal(fmt.Errorf("%w: watcher: %v", ErrFailure, err))
+			case <-timer.C:
+				select {
+				case <-ctx.Done():
+					return
+				case ch <- last:
+				}
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
@@ -0,0 +1,68 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchDirs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dir := t.TempDir()
+	changes, err := WatchDirs(ctx, []string{filepath.Join(dir, "missing"), dir}, 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "v0.yaml")
+	for i := 0; i < 3; i++ {
+		if err := os.WriteFile(path, []byte("presets: {}\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case got := <-changes:
+		if got != path {
+			t.Errorf("got %q, want %q", got, path)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no change sent")
+	}
+	select {
+	case got := <-changes:
+		t.Errorf("got %q again, want a single change", got)
+	case <-time.After(200 * time.Millisecond):
+	}
+	cancel()
+	if _, ok := <-changes; ok {
+		t.Error("changes not closed with context")
+	}
+}
+
+func TestWatchDirsMissing(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "missing")
+	if _, err := WatchDirs(context.Background(), []string{dir}, time.Second); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v, want %v", err, ErrNotFound)
+	}
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
@@ -0,0 +1,173 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Realtime watchdog default values
+const (
+	defaultRTWindow   = 10 * time.Second
+	defaultRTCooldown = 10 * time.Minute
+)
+
+// RTLimit is the share of one CPU, in percent, that a process moved to some
+// realtime class can use during Window. Beyond, the process is demoted to
+// the other class, and not promoted again during Cooldown.
+type RTLimit struct {
+	Share    float64       `mapstructure:"share" yaml:"share" json:"share"`
+	Window   time.Duration `mapstructure:"window" yaml:"window,omitempty" json:"window,omitempty"`
+	Cooldown time.Duration `mapstructure:"cooldown" yaml:"cooldown,omitempty" json:"cooldown,omitempty"`
+}
+
+// Validate checks the limit and sets default values.
+func (l *RTLimit) Validate() error {
+	if l.Share <= 0 || l.Share > float64(100*numCPU) {
+		return fmt.Errorf("%w: watchdog: share: %v", ErrInvalid, l.Share)
+	}
+	if l.Window <= 0 {
+		l.Window = defaultRTWindow
+	}
+	if l.Cooldown <= 0 {
+		l.Cooldown = defaultRTCooldown
+	}
+	return nil
+}
+
+// rtSample is the CPU time, in seconds, that some process used until some
+// time.
+type rtSample struct {
+	StartTime uint64
+	since     time.Time
+	cpu       float64
+}
+
+// Match returns whether p is the sampled process, even when renamed since.
+func (s *rtSample) Match(p *Proc) bool {
+	return s.StartTime == p.StartTime
+}
+
+// Watchdog demotes the realtime processes that use too much CPU time.
+type Watchdog struct {
+	mu      sync.Mutex
+	limit   RTLimit
+	samples map[int]*rtSample // per pid
+}
+
+// NewWatchdog returns the watchdog for the limit found in watchdog key,
+// disabled when none.
+func NewWatchdog() (*Watchdog, error) {
+	return newWatchdog(viper.GetViper())
+}
+
+func newWatchdog(v *viper.Viper) (*Watchdog, error) {
+	var limit RTLimit
+	if err := v.UnmarshalKey("watchdog", &limit); err != nil {
+		return nil, fmt.Errorf("%w: watchdog: %v", ErrInvalid, err)
+	}
+	if limit.Share != 0 {
+		if err := limit.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return &Watchdog{limit: limit, samples: make(map[int]*rtSample)}, nil
+}
+
+// Replace uses the limit of next watchdog, sampling again the processes when
+// changed. The demoted processes are promoted again after their cooldown.
+func (w *Watchdog) Replace(next *Watchdog) {
+	if w == nil || next == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.limit != next.limit {
+		w.limit, w.samples = next.limit, make(map[int]*rtSample)
+	}
+}
+
+// Update reads the CPU time used by the processes that their rule moved to
+// some realtime class, then demotes those that used more than the limit
+// during the last window. The processes whose cooldown ended are reviewed
+// again, and whether some are is returned.
+func (w *Watchdog) Update(now time.Time, tag string, std *Streams) bool {
+	if w == nil {
+		return false
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.limit.Share == 0 { // disabled
+		return procState.Expire(now)
+	}
+	seen := make(map[int]bool)
+	for _, p := range SelectedProcs(procState.Realtime(), GetFilterer("all")) {
+		if p.Policy != SCHED_FIFO && p.Policy != SCHED_RR {
+			continue
+		}
+		seen[p.Pid] = true
+		sample, found := w.samples[p.Pid]
+		if !(found) || !(sample.Match(p)) {
+			w.samples[p.Pid] = &rtSample{
+				StartTime: p.StartTime, since: now, cpu: p.CPUTime(),
+			}
+			continue
+		}
+		elapsed := now.Sub(sample.since)
+		if elapsed < w.limit.Window {
+			continue
+		}
+		share := 100 * (p.CPUTime() - sample.cpu) / elapsed.Seconds()
+		sample.since, sample.cpu = now, p.CPUTime()
+		if share <= w.limit.Share {
+			continue
+		}
+		inform("watchdog", fmt.Sprintf(
+			"%s[%d]: %.0f%% of CPU time over %v, demoting for %v",
+			p.Comm, p.Pid, share, elapsed.Round(time.Second), w.limit.Cooldown,
+		))
+		if err := demote(p, tag, std); err != nil {
+			nonfatal(err)
+			continue
+		}
+		procState.Demote(p, now.Add(w.limit.Cooldown))
+	}
+	for pid := range w.samples {
+		if !(seen[pid]) {
+			delete(w.samples, pid)
+		}
+	}
+	return procState.Expire(now)
+}
+
+// demote moves all the threads of the process to the other class.
+func demote(p *Proc, tag string, std *Streams) error {
+	groupjob := NewProcGroupJob([]*Proc{p})
+	job := groupjob.leader
+	job.Request.Proc = p
+	job.Rule = Rule{BaseProfile: BaseProfile{Sched: "other"}}
+	job.Rule.SetCredentials()
+	job.AdjustSchedRTPrio(p.Pid)
+	groupjob.Commands = job.Commands
+	return groupjob.Run(tag, std)
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
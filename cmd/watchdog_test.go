@@ -0,0 +1,68 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestReloadControlCacheWatchdog(t *testing.T) {
+	const presets = "presets:\n  appgroups:\n    none: {}\n  rules:\n    mpv:\n      nice: 5\n"
+	tests := []struct {
+		name    string
+		config  string
+		want    RTLimit
+		wantErr error
+	}{
+		{"set", "watchdog:\n  share: 50\n", RTLimit{Share: 50, Window: defaultRTWindow, Cooldown: defaultRTCooldown}, nil},
+		{"changed", "watchdog:\n  share: 80\n  window: 5s\n", RTLimit{Share: 80, Window: 5 * time.Second, Cooldown: defaultRTCooldown}, nil},
+		{"removed", "", RTLimit{}, nil},
+		{"invalid", "watchdog:\n  share: -1\n", RTLimit{Share: 90, Window: defaultRTWindow, Cooldown: defaultRTCooldown}, ErrInvalid},
+		{"unreadable", "watchdog: [\n", RTLimit{Share: 90, Window: defaultRTWindow, Cooldown: defaultRTCooldown}, ErrInvalid},
+	}
+	defer viper.Set("confdirs", viper.GetStringSlice("confdirs"))
+	defer func(caches *UserCaches) { controlCaches.Store(caches) }(controlCaches.Load())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := NewPresetCache()
+			controlCaches.Store(NewUserCaches(&pc, false))
+			watchdog := &Watchdog{
+				limit:   RTLimit{Share: 90, Window: defaultRTWindow, Cooldown: defaultRTCooldown},
+				samples: make(map[int]*rtSample),
+			}
+			viper.Set("confdirs", []string{writeConfig(t, presets+tt.config)})
+			err := reloadControlCache(&Throttler{}, watchdog)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if watchdog.limit != tt.want {
+				t.Errorf("got limit %+v, want %+v", watchdog.limit, tt.want)
+			}
+			// presets kept with the previous limit
+			_, found := controlCaches.Load().Base.Rules["mpv"]
+			if found != (err == nil) {
+				t.Errorf("got presets reloaded %v with error %v", found, err)
+			}
+		})
+	}
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet:
@@ -19,6 +19,7 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package procfs
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"strings"
@@ -155,15 +156,50 @@ type ProcStat struct {
 	ExitCode            int    `json:"exit_code"`             // (52) %d
 }
 
+// ErrNoComm is the error of the stat lines without any command name.
+var ErrNoComm = errors.New("no command name")
+
+// StatError reports some malformed stat line.
+type StatError struct {
+	Pid   int    // when parsed
+	Field int    // number of the field, from (1)
+	Value string // when unparsable
+	Err   error
+}
+
+func (e *StatError) Error() string {
+	msg := "stat"
+	if e.Pid > 0 {
+		msg += fmt.Sprintf(": pid %d", e.Pid)
+	}
+	msg += fmt.Sprintf(": field (%d)", e.Field)
+	if e.Value != "" {
+		msg += fmt.Sprintf(": %q", e.Value)
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+func (e *StatError) Unwrap() error {
+	return e.Err
+}
+
+// Load parses the stat line in buffer. The command name, that may hold spaces
+// and parentheses, ends at the last closing parenthesis. Some *StatError is
+// returned when the line is malformed.
 func (stat *ProcStat) Load(buffer string) (err error) {
 	stat.stat = buffer
-	var comm string
+	// command name may hold spaces and parentheses
+	open, end := strings.IndexByte(buffer, '('), strings.LastIndexByte(buffer, ')')
+	if open < 0 || end < open {
+		return &StatError{Field: 2, Err: ErrNoComm}
+	}
+	if _, err = fmt.Sscan(buffer[:open], &stat.Pid); err != nil { // (1) %d *
+		return &StatError{Field: 1, Value: strings.TrimSpace(buffer[:open]), Err: err}
+	}
+	stat.Comm = buffer[open+1 : end] // (2) %s *
 	// parse
-	_, err = fmt.Sscan(
-		buffer,
-		&stat.Pid, // (1) %d *
-		&comm,
-		// &stat.Comm,				// (2) %s *
+	n, err := fmt.Sscan(
+		buffer[end+1:],
 		&stat.State,               // (3) %c *
 		&stat.Ppid,                // (4) %d *
 		&stat.Pgrp,                // (5) %d *
@@ -215,17 +251,24 @@ func (stat *ProcStat) Load(buffer string) (err error) {
 		&stat.EnvEnd,              // (51) %lu
 		&stat.ExitCode,            // (52) %d
 	)
-	stat.Comm = strings.Trim(comm, "()")
-	return
+	if err != nil {
+		e := &StatError{Pid: stat.Pid, Field: n + 3, Err: err}
+		if fields := strings.Fields(buffer[end+1:]); n < len(fields) {
+			e.Value = fields[n]
+		}
+		return e
+	}
+	return nil
 }
 
-func (stat *ProcStat) Read(pid int) (err error) {
+func (stat *ProcStat) Read(pid int) error {
 	// read stat data for pid
-	if data, err := GetResource(pid, "stat"); err == nil {
-		// load
-		err = stat.Load(string(data))
+	data, err := GetResource(pid, "stat")
+	if err != nil {
+		return err
 	}
-	return
+	// load
+	return stat.Load(string(data))
 }
 
 func (stat *ProcStat) GoString() string {
@@ -0,0 +1,78 @@
+/*
+Copyright © 2026 David Guadalupe <guadalupe.david@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package procfs
+
+import (
+	"errors"
+	"testing"
+)
+
+// statFields are the fields of some stat line, after the command name.
+const statFields = "S 14064 14063 14063 0 -1 4194304 5898 6028 495 394 487 64 88 68 39 19 1 0 1256778 18685952 2655 4294967295 4620288 7319624 3219630688 0 0 0 0 2 536891909 1 0 0 17 0 0 0 0 0 0 8366744 8490776 38150144 3219638342 3219638506 3219638506 3219644398 0"
+
+func TestProcStatLoad(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		wantComm string
+		wantErr  *StatError // when malformed, without Err
+	}{
+		{"plain", "14066 (nvim) " + statFields, "nvim", nil},
+		{"spaces", "14066 (Web Content) " + statFields, "Web Content", nil},
+		{"parentheses", "14066 (a) (b)) " + statFields, "a) (b)", nil},
+		{"empty", "14066 () " + statFields, "", nil},
+		{"no command name", "14066 nvim " + statFields, "", &StatError{Field: 2}},
+		{"bad pid", "pid (nvim) " + statFields, "", &StatError{Field: 1, Value: "pid"}},
+		{"bad field", "14066 (nvim) S 14064 group 14063", "", &StatError{Pid: 14066, Field: 5, Value: "group"}},
+		{"truncated", "14066 (nvim) S 14064", "", &StatError{Pid: 14066, Field: 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stat ProcStat
+			err := stat.Load(tt.line)
+			if tt.wantErr != nil {
+				var got *StatError
+				if !(errors.As(err, &got)) {
+					t.Fatalf("got error %v, want *StatError", err)
+				}
+				if got.Pid != tt.wantErr.Pid || got.Field != tt.wantErr.Field || got.Value != tt.wantErr.Value {
+					t.Errorf("got %+v, want %+v", got, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %v", err)
+			}
+			if stat.Pid != 14066 || stat.Comm != tt.wantComm {
+				t.Errorf("got (%d, %q), want (14066, %q)", stat.Pid, stat.Comm, tt.wantComm)
+			}
+			if stat.State != "S" || stat.Ppid != 14064 || stat.Pgrp != 14063 ||
+				stat.Nice != 19 || stat.StartTime != 1256778 || stat.EnvEnd != 3219644398 {
+				t.Errorf("fields shifted: %s", stat.String())
+			}
+		})
+	}
+}
+
+func TestStatErrorNoComm(t *testing.T) {
+	var stat ProcStat
+	if err := stat.Load("14066 nvim " + statFields); !(errors.Is(err, ErrNoComm)) {
+		t.Errorf("got error %v, want %v", err, ErrNoComm)
+	}
+}
+
+// vim: set ft=go fdm=indent ts=2 sw=2 tw=79 noet: